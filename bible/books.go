@@ -0,0 +1,162 @@
+package bible
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Maximum number of names suggested for unknown book.
+const maxSuggestions = 3
+
+// BookError is returned for book name which can't be resolved,
+// Suggestions hold names close to it.
+type BookError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *BookError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("book %q does not exist", e.Name)
+	}
+	return fmt.Sprintf("book %q does not exist, did you mean: %s?", e.Name, strings.Join(e.Suggestions, ", "))
+}
+
+// bookIndex resolves book names regardless of case, diacritics and
+// trailing dots. Unique prefix of name is accepted as well.
+type bookIndex struct {
+	// Book number by folded name.
+	numbers map[string]int
+	// Original name by folded name, used in suggestions.
+	names map[string]string
+	// Folded names in order.
+	keys []string
+}
+
+func newBookIndex(bookName map[int][]string) *bookIndex {
+	bi := &bookIndex{numbers: make(map[string]int), names: make(map[string]string)}
+	numbers := make([]int, 0, len(bookName))
+	for num := range bookName {
+		numbers = append(numbers, num)
+	}
+	sort.Ints(numbers)
+	for _, num := range numbers {
+		for _, name := range bookName[num] {
+			key := foldBookName(name)
+			if _, ok := bi.numbers[key]; ok {
+				continue
+			}
+			bi.numbers[key] = num
+			bi.names[key] = name
+			bi.keys = append(bi.keys, key)
+		}
+	}
+	sort.Strings(bi.keys)
+	return bi
+}
+
+// foldBookName lowercases name, folds diacritics and drops dots and spaces.
+func foldBookName(name string) string {
+	name = foldDiacritics.Replace(strings.ToLower(name))
+	return strings.NewReplacer(".", "", " ", "").Replace(name)
+}
+
+func (bi *bookIndex) resolve(name string) (int, error) {
+	key := foldBookName(name)
+	if num, ok := bi.numbers[key]; ok {
+		return num, nil
+	}
+	if key == "" {
+		return 0, &BookError{Name: name}
+	}
+
+	// Prefix of names of single book.
+	var prefixed []string
+	books := make(map[int]bool)
+	for _, k := range bi.keys {
+		if strings.HasPrefix(k, key) {
+			prefixed = append(prefixed, k)
+			books[bi.numbers[k]] = true
+		}
+	}
+	if len(books) == 1 {
+		return bi.numbers[prefixed[0]], nil
+	}
+	if len(books) > 1 {
+		return 0, &BookError{Name: name, Suggestions: bi.suggest(prefixed)}
+	}
+
+	// Misspelled name, names within edit distance are suggested.
+	maxDistance := 1
+	if len([]rune(key)) > 4 {
+		maxDistance = 2
+	}
+	type candidate struct {
+		key      string
+		distance int
+	}
+	var candidates []candidate
+	for _, k := range bi.keys {
+		if d := levenshtein(key, k); d <= maxDistance {
+			candidates = append(candidates, candidate{k, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	keys := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		keys = append(keys, c.key)
+	}
+	return 0, &BookError{Name: name, Suggestions: bi.suggest(keys)}
+}
+
+// suggest returns original names of keys, one for every book.
+func (bi *bookIndex) suggest(keys []string) []string {
+	var names []string
+	books := make(map[int]bool)
+	for _, k := range keys {
+		if books[bi.numbers[k]] {
+			continue
+		}
+		books[bi.numbers[k]] = true
+		names = append(names, bi.names[k])
+		if len(names) == maxSuggestions {
+			break
+		}
+	}
+	return names
+}
+
+// levenshtein returns edit distance of a and b counted in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
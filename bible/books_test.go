@@ -0,0 +1,77 @@
+package bible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_bookIndex_resolve(t *testing.T) {
+	bi := newBookIndex(map[int][]string{
+		1:  {"rodz", "rodzaju"},
+		2:  {"wy", "wj", "wyjscia"},
+		3:  {"ka", "kapl", "kaplanska", "kapłańska"},
+		13: {"1kro"},
+		21: {"koh"},
+		42: {"luk", "łuk", "lk"},
+		46: {"1kor"},
+		51: {"kol"},
+	})
+
+	tests := []struct {
+		name    string
+		book    string
+		want    int
+		wantErr *BookError
+	}{
+		{"exact", "rodz", 1, nil},
+		{"case", "RODZ", 1, nil},
+		{"trailing dot", "Rdz.", 0, &BookError{Name: "Rdz.", Suggestions: []string{"rodz"}}},
+		{"abbreviation with dot", "Rodz.", 1, nil},
+		{"diacritics in name", "Łk", 42, nil},
+		{"diacritics missing", "kaplańska", 3, nil},
+		{"folded name", "Kapłanska", 3, nil},
+		{"unique prefix", "wyj", 2, nil},
+		{"unique prefix of diacritic name", "łu", 42, nil},
+		{"number with space", "1 Kor", 46, nil},
+		{"ambiguous prefix", "ko", 0, &BookError{Name: "ko", Suggestions: []string{"koh", "kol"}}},
+		{"ambiguous numbered prefix", "1k", 0, &BookError{Name: "1k", Suggestions: []string{"1kor", "1kro"}}},
+		{"misspelled", "rodx", 0, &BookError{Name: "rodx", Suggestions: []string{"rodz"}}},
+		{"misspelled long", "wyjsica", 0, &BookError{Name: "wyjsica", Suggestions: []string{"wyjscia"}}},
+		{"unknown", "xyz", 0, &BookError{Name: "xyz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bi.resolve(tt.book)
+			if tt.wantErr == nil {
+				if err != nil || got != tt.want {
+					t.Errorf("resolve(%q) = %d, %v, want %d", tt.book, got, err, tt.want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("resolve(%q) error = %#v, want %#v", tt.book, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBookError_Error(t *testing.T) {
+	err := &BookError{Name: "ko", Suggestions: []string{"koh", "kol"}}
+	if got, want := err.Error(), `book "ko" does not exist, did you mean: koh, kol?`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func Test_service_GetTextByReference_bookNames(t *testing.T) {
+	s := newTestService(t)
+
+	for _, ref := range []string{"rodz 1,1", "Rodz. 1,1", "RODZAJU 1,1", "rodzaj 1,1"} {
+		if got, err := s.GetTextByReference("", ref); err != nil || got != "rodz 1,1\n 1 Na początku Bóg stworzył niebo i ziemię." {
+			t.Errorf("GetTextByReference(%q) = %q, %v", ref, got, err)
+		}
+	}
+	_, err := s.GetTextByReference("", "rodx 1,1")
+	if pe, ok := err.(*ParseError); !ok || pe.Pos != 0 || pe.Err.Error() != `book "rodx" does not exist, did you mean: rodz?` {
+		t.Errorf("GetTextByReference() error = %v, want suggestion", err)
+	}
+}
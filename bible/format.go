@@ -0,0 +1,131 @@
+package bible
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Style controls how Format writes references. Zero value gives
+// abbreviated references, e.g. "rodz 1,1-3".
+type Style struct {
+	// FullNames writes longest known name of book instead of
+	// its abbreviation, e.g. "rodzaju".
+	FullNames bool
+	// Chapters writes spans of whole chapters without verses,
+	// e.g. "rodz 1-2".
+	Chapters bool
+	// Separator of chapter and verse, "," when empty.
+	VerseSeparator string
+	// Separator of range ends, "-" when empty.
+	RangeSeparator string
+}
+
+func (st Style) verseSeparator() string {
+	if st.VerseSeparator == "" {
+		return ","
+	}
+	return st.VerseSeparator
+}
+
+func (st Style) rangeSeparator() string {
+	if st.RangeSeparator == "" {
+		return "-"
+	}
+	return st.RangeSeparator
+}
+
+// Format returns reference of verses in style, which parses back into
+// the same verses. Book is left out when span starts in book where
+// previous one ends, e.g. "rodz 1,1; 2,1".
+func (s *service) Format(verses []*Verse, style Style) (string, error) {
+	refs := make([]string, 0, len(verses))
+	var book string
+	for _, v := range verses {
+		ref, end, err := s.formatSpan(v, style, book)
+		if err != nil {
+			return "", err
+		}
+		refs = append(refs, ref)
+		book = end
+	}
+	return strings.Join(refs, "; "), nil
+}
+
+// formatSpan returns reference of v and book token where it ends,
+// book name is left out when span starts in book.
+func (s *service) formatSpan(v *Verse, style Style, book string) (string, string, error) {
+	end := v.Start()
+	if v.IsRange() {
+		end = v.End()
+	}
+	startLabel, ok := s.store.labelAt(v.Start())
+	if !ok {
+		return "", "", fmt.Errorf("can't find verse index %d", v.Start())
+	}
+	endLabel, ok := s.store.labelAt(end)
+	if !ok {
+		return "", "", fmt.Errorf("can't find verse index %d", end)
+	}
+
+	chapterStart, err := s.GetChapterStartIndex(v.Start())
+	if err != nil {
+		return "", "", err
+	}
+	whole := style.Chapters && v.Start() == chapterStart && s.GetChapterEndIndex(end) == end
+
+	var ref []string
+	if startLabel.GetBook() != book {
+		name, err := s.styledBookName(startLabel, style)
+		if err != nil {
+			return "", "", err
+		}
+		ref = append(ref, name, " ")
+	}
+	point := func(l Label) string {
+		if whole {
+			return s.getChapterFromLabel(l)
+		}
+		return s.getChapterFromLabel(l) + style.verseSeparator() + s.getVerseFromLabel(l)
+	}
+
+	switch {
+	case whole && sameChapter(startLabel, endLabel), v.IsSingle():
+		ref = append(ref, point(startLabel))
+	case sameChapter(startLabel, endLabel):
+		ref = append(ref, point(startLabel), style.rangeSeparator(), s.getVerseFromLabel(endLabel))
+	case startLabel.GetBook() == endLabel.GetBook():
+		ref = append(ref, point(startLabel), style.rangeSeparator(), point(endLabel))
+	default:
+		// Cross book range names both books.
+		name, err := s.styledBookName(endLabel, style)
+		if err != nil {
+			return "", "", err
+		}
+		ref = append(ref, point(startLabel), " ", style.rangeSeparator(), " ", name, " ", point(endLabel))
+	}
+	return strings.Join(ref, ""), endLabel.GetBook(), nil
+}
+
+// styledBookName returns name of book of label in style.
+func (s *service) styledBookName(l Label, style Style) (string, error) {
+	if !style.FullNames {
+		return s.getBookFromLabel(l)
+	}
+	book, err := strconv.Atoi(l.GetBook())
+	if err != nil {
+		return "", err
+	}
+	names, err := s.GetBookNames(book)
+	if err != nil {
+		return "", err
+	}
+	name := names[0]
+	for _, n := range names[1:] {
+		if utf8.RuneCountInString(n) > utf8.RuneCountInString(name) {
+			name = n
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,129 @@
+package bible
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/go-kit/kit/log"
+)
+
+func newCanonService(t *testing.T) *service {
+	texts := []TextSource{{Code: "bt", Path: "testdata/canon.txt"}}
+	s, err := New("../data/ksiegi.txt", texts, "testdata/plan.csv", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.(*service)
+}
+
+func Test_service_Format(t *testing.T) {
+	s := newCanonService(t)
+
+	tests := []struct {
+		name   string
+		verses []*Verse
+		style  Style
+		want   string
+	}{
+		{"single", []*Verse{{start: 2}}, Style{}, "rodz 1,3"},
+		{"range", []*Verse{{start: 0, end: 1}}, Style{}, "rodz 1,1-2"},
+		{"part", []*Verse{{start: 6}}, Style{}, "wy 1,2a"},
+		{"cross chapter", []*Verse{{start: 1, end: 3}}, Style{}, "rodz 1,2-2,1"},
+		{"cross book", []*Verse{{start: 4, end: 7}}, Style{}, "rodz 2,2 - wy 1,2b"},
+		{"whole chapter", []*Verse{{start: 3, end: 4}}, Style{}, "rodz 2,1-2"},
+		{"whole chapter in chapters", []*Verse{{start: 3, end: 4}}, Style{Chapters: true}, "rodz 2"},
+		{"chapter range", []*Verse{{start: 0, end: 4}}, Style{Chapters: true}, "rodz 1-2"},
+		{"cross book chapters", []*Verse{{start: 3, end: 8}}, Style{Chapters: true}, "rodz 2 - wy 1"},
+		{"single verse chapter", []*Verse{{start: 9}}, Style{Chapters: true}, "ka 1"},
+		{"list", []*Verse{{start: 0}, {start: 3}, {start: 5}}, Style{}, "rodz 1,1; 2,1; wy 1,1"},
+		{"list after cross book", []*Verse{{start: 4, end: 5}, {start: 8}}, Style{}, "rodz 2,2 - wy 1,1; 1,3"},
+		{"full names", []*Verse{{start: 0}, {start: 12}}, Style{FullNames: true}, "rodzaju 1,1; liczb 1,1"},
+		{"separators", []*Verse{{start: 1, end: 3}}, Style{VerseSeparator: ":", RangeSeparator: "–"}, "rodz 1:2–2:1"},
+		{"numbered book", []*Verse{{start: 20, end: 21}}, Style{}, "1kor 16,1-2"},
+		{"cross numbered books", []*Verse{{start: 20, end: 23}}, Style{}, "1kor 16,1 - 2kor 1,2"},
+		{"cross numbered books chapters", []*Verse{{start: 20, end: 23}}, Style{Chapters: true}, "1kor 16 - 2kor 1"},
+		{"cross book to numbered", []*Verse{{start: 15, end: 16}}, Style{}, "powt 1,2 - 1s 1,1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Format(tt.verses, tt.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_service_VerseHeader_crossBook(t *testing.T) {
+	s := newCanonService(t)
+
+	got, err := s.VerseHeader(&Verse{start: 0, end: 13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "rodz 1,1 - li 1,2"; got != want {
+		t.Errorf("VerseHeader() = %q, want %q", got, want)
+	}
+}
+
+// Test_service_Format_roundTrip checks that formatted spans of any
+// verses of text parse back into the same spans. Spans ending in
+// numbered books are checked always, random ones may miss them.
+func Test_service_Format_roundTrip(t *testing.T) {
+	s := newCanonService(t)
+	n := s.store.maxIndex() + 1
+
+	roundTrip := func(spans [][2]uint16, fullNames, chapters, colon, dash bool) bool {
+		if len(spans) == 0 {
+			return true
+		}
+		verses := make([]*Verse, 0, len(spans))
+		for _, span := range spans {
+			start, end := int(span[0])%n, int(span[1])%n
+			if start > end {
+				start, end = end, start
+			}
+			if start == end {
+				end = 0
+			}
+			verses = append(verses, &Verse{start: start, end: end})
+		}
+		style := Style{FullNames: fullNames, Chapters: chapters}
+		if colon {
+			style.VerseSeparator = ":"
+		}
+		if dash {
+			style.RangeSeparator = "–"
+		}
+
+		ref, err := s.Format(verses, style)
+		if err != nil {
+			t.Logf("Format(%v) error = %v", verses, err)
+			return false
+		}
+		got, err := NewParser(strings.NewReader(ref), s).Parse()
+		if err != nil || !reflect.DeepEqual(got, verses) {
+			t.Logf("Parse(%q) = %v, %v, want %v", ref, got, err, verses)
+			return false
+		}
+		return true
+	}
+	numbered := [][2]uint16{{20, 23}, {16, 19}, {15, 16}, {17, 22}}
+	for _, span := range numbered {
+		for _, chapters := range []bool{false, true} {
+			for _, fullNames := range []bool{false, true} {
+				if !roundTrip([][2]uint16{span}, fullNames, chapters, false, false) {
+					t.Errorf("round trip of %v failed, full names %v, chapters %v", span, fullNames, chapters)
+				}
+			}
+		}
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"unicode"
+	"unicode/utf8"
 )
 
 //go:generate stringer -type=Token
@@ -17,6 +19,8 @@ const (
 	DASH
 	EOF
 	WS // WhiteSpace
+	DOT
+	SEMICOLON
 
 	eof rune = rune(0)
 )
@@ -26,15 +30,17 @@ func isWhitespace(ch rune) bool {
 }
 
 func isLetter(ch rune) bool {
-	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+	return unicode.IsLetter(ch)
 }
 
+// isComma reports separator of chapter and verse, e.g. "J 3,16"
+// or "J 3:16".
 func isComma(ch rune) bool {
-	return ch == ','
+	return ch == ',' || ch == ':'
 }
 
 func isDash(ch rune) bool {
-	return (ch == '-' || ch == '—')
+	return (ch == '-' || ch == '–' || ch == '—')
 }
 
 func isDigit(ch rune) bool {
@@ -43,19 +49,80 @@ func isDigit(ch rune) bool {
 
 type Scanner struct {
 	r *bufio.Reader
+	// Last token other than whitespace.
+	last Token
+	// Number of runes read.
+	pos int
 }
 
 func NewScanner(r io.Reader) *Scanner {
 	return &Scanner{r: bufio.NewReader(r)}
 }
 
+// peekNRunes returns next n runes without consuming them,
+// error is returned when input ends earlier.
 func (s *Scanner) peekNRunes(n int) (string, error) {
-	// usually rune is 2 bytes
-	b, err := s.r.Peek(n)
-	if err != nil {
-		return string(b), err
+	// Peek returns available bytes along with error at the end of input.
+	b, err := s.r.Peek(n * utf8.UTFMax)
+	var i, runes int
+	for i < len(b) && runes < n {
+		_, size := utf8.DecodeRune(b[i:])
+		i += size
+		runes++
+	}
+	if runes < n {
+		if err == nil {
+			err = io.EOF
+		}
+		return string(b[:i]), err
+	}
+	return string(b[:i]), nil
+}
+
+// bookAhead reports whether digit at input starts book name,
+// e.g. "1kor" or "1 kor".
+func (s *Scanner) bookAhead() bool {
+	st, _ := s.peekNRunes(8)
+	runes := []rune(st)
+	if len(runes) < 2 {
+		return false
+	}
+	if isLetter(runes[1]) {
+		return true
+	}
+	for _, ch := range runes[1:] {
+		if !isWhitespace(ch) {
+			return isLetter(ch)
+		}
+	}
+	return false
+}
+
+// rangeBookAhead reports whether digit after dash starts book of cross
+// book range, e.g. "2kor 1" of "1kor 16 - 2kor 1". Book is followed
+// by chapter, partial verse like "2a" of "J 1,1-2a" isn't.
+func (s *Scanner) rangeBookAhead() bool {
+	st, _ := s.peekNRunes(40)
+	runes := []rune(st)
+	i := 1
+	for i < len(runes) && isWhitespace(runes[i]) {
+		i++
+	}
+	letters := 0
+	for i < len(runes) && isLetter(runes[i]) {
+		i++
+		letters++
+	}
+	if letters == 0 {
+		return false
+	}
+	if i < len(runes) && runes[i] == '.' {
+		i++
+	}
+	for i < len(runes) && isWhitespace(runes[i]) {
+		i++
 	}
-	return string(b), nil
+	return i < len(runes) && isDigit(runes[i])
 }
 
 func (s *Scanner) read() rune {
@@ -63,19 +130,30 @@ func (s *Scanner) read() rune {
 	if err != nil {
 		return eof
 	}
+	s.pos++
 	return ch
 }
 
-func (s *Scanner) unread() { _ = s.r.UnreadRune() }
+func (s *Scanner) unread() {
+	if s.r.UnreadRune() == nil {
+		s.pos--
+	}
+}
 
 func (s *Scanner) Scan() (tok Token, lit string) {
+	tok, lit = s.scan()
+	if tok != WS {
+		s.last = tok
+	}
+	return tok, lit
+}
+
+func (s *Scanner) scan() (tok Token, lit string) {
 	// Read next rune.
-	var ch rune
+	ch := eof
 	st, err := s.peekNRunes(1)
-	if err != nil {
-		ch = eof
-	} else {
-		ch = rune(st[0])
+	if err == nil {
+		ch, _ = utf8.DecodeRuneInString(st)
 	}
 
 	switch {
@@ -84,11 +162,16 @@ func (s *Scanner) Scan() (tok Token, lit string) {
 	case isLetter(ch):
 		return s.scanBook()
 	case isDigit(ch):
-		// Maybe book starts with number...
-		st, err := s.peekNRunes(2)
-		if err == nil {
-			nextch := rune(st[1])
-			if isLetter(nextch) {
+		// Maybe book starts with number, unless verse is expected,
+		// e.g. "1a" of "J 1,1a" isn't book.
+		switch s.last {
+		case COMMA, DOT:
+		case DASH:
+			if s.rangeBookAhead() {
+				return s.scanBook()
+			}
+		default:
+			if s.bookAhead() {
 				return s.scanBook()
 			}
 		}
@@ -103,6 +186,10 @@ func (s *Scanner) Scan() (tok Token, lit string) {
 		return COMMA, string(ch)
 	case isDash(ch):
 		return DASH, string(ch)
+	case ch == '.':
+		return DOT, string(ch)
+	case ch == ';':
+		return SEMICOLON, string(ch)
 	}
 	return ILLEGAL, string(ch)
 }
@@ -128,9 +215,15 @@ Loop:
 	return WS, buf.String()
 }
 
+// scanBook consumes book name, space after leading number
+// and trailing dot of abbreviation are dropped.
 func (s *Scanner) scanBook() (tok Token, lit string) {
 	var buf bytes.Buffer
-	buf.WriteRune(s.read())
+	first := s.read()
+	buf.WriteRune(first)
+	if isDigit(first) {
+		s.skipWhitespace()
+	}
 
 Loop:
 	for {
@@ -145,9 +238,25 @@ Loop:
 			_, _ = buf.WriteRune(ch)
 		}
 	}
+	if ch := s.read(); ch != '.' && ch != eof {
+		s.unread()
+	}
 	return BOOK, buf.String()
 }
 
+func (s *Scanner) skipWhitespace() {
+	for {
+		ch := s.read()
+		if ch == eof {
+			return
+		}
+		if !isWhitespace(ch) {
+			s.unread()
+			return
+		}
+	}
+}
+
 func (s *Scanner) scanNextNum() (tok Token, lit string) {
 	var buf bytes.Buffer
 	buf.WriteRune(s.read())
@@ -165,5 +274,24 @@ Loop:
 			_, _ = buf.WriteRune(ch)
 		}
 	}
+	if suffix, ok := s.verseSuffix(); ok {
+		s.read()
+		buf.WriteRune(suffix)
+	}
 	return NEXT_NUM, buf.String()
 }
+
+// verseSuffix reports whether number is followed by single letter of
+// partial verse, e.g. "a" of "16a".
+func (s *Scanner) verseSuffix() (rune, bool) {
+	st, _ := s.peekNRunes(2)
+	runes := []rune(st)
+	if len(runes) == 0 {
+		return 0, false
+	}
+	ch := unicode.ToLower(runes[0])
+	if ch < 'a' || ch > 'z' || (len(runes) > 1 && isLetter(runes[1])) {
+		return 0, false
+	}
+	return ch, true
+}
@@ -137,3 +137,51 @@ func TestScanner_ScanChapterRange1(t *testing.T) {
 		t.Errorf("wrong tokens")
 	}
 }
+
+func TestScanner_ScanUnicode(t *testing.T) {
+	tests := []struct {
+		input    string
+		tokens   []Token
+		literals []string
+	}{
+		{"Łk 2,1", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM}, []string{"Łk", " ", "2", ",", "1"}},
+		{"kapłańska 1", []Token{BOOK, WS, NEXT_NUM}, []string{"kapłańska", " ", "1"}},
+		{"Ś", []Token{BOOK}, []string{"Ś"}},
+		{"Rdz. 1,1", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM}, []string{"Rdz", " ", "1", ",", "1"}},
+		{"1 Kor 13,4–7", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM, DASH, NEXT_NUM}, []string{"1Kor", " ", "13", ",", "4", "–", "7"}},
+		{"1kor 1", []Token{BOOK, WS, NEXT_NUM}, []string{"1kor", " ", "1"}},
+		{"ps 1 2", []Token{BOOK, WS, NEXT_NUM, WS, NEXT_NUM}, []string{"ps", " ", "1", " ", "2"}},
+		{"J 3,16a", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM}, []string{"J", " ", "3", ",", "16a"}},
+		{"Mk 5,1b-3", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM, DASH, NEXT_NUM}, []string{"Mk", " ", "5", ",", "1b", "-", "3"}},
+		{"1 J 1,1a.2B", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM, DOT, NEXT_NUM}, []string{"1J", " ", "1", ",", "1a", ".", "2b"}},
+		{"ps 1,1 ab", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM, WS, BOOK}, []string{"ps", " ", "1", ",", "1", " ", "ab"}},
+		{"ps 1,1ab", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM, BOOK}, []string{"ps", " ", "1", ",", "1", "ab"}},
+		{"J 3,16; 4,1.3", []Token{BOOK, WS, NEXT_NUM, COMMA, NEXT_NUM, SEMICOLON, WS, NEXT_NUM, COMMA, NEXT_NUM, DOT, NEXT_NUM}, []string{"J", " ", "3", ",", "16", ";", " ", "4", ",", "1", ".", "3"}},
+	}
+	for _, tt := range tests {
+		s := NewScanner(strings.NewReader(tt.input))
+		var tokens []Token
+		var literals []string
+		for {
+			tok, lit := s.Scan()
+			if tok == EOF {
+				break
+			}
+			tokens = append(tokens, tok)
+			literals = append(literals, lit)
+		}
+		if !reflect.DeepEqual(tokens, tt.tokens) || !reflect.DeepEqual(literals, tt.literals) {
+			t.Errorf("Scan(%q) = %v %q, want %v %q", tt.input, tokens, literals, tt.tokens, tt.literals)
+		}
+	}
+}
+
+func TestScanner_peekNRunesUnicode(t *testing.T) {
+	s := NewScanner(strings.NewReader("Łk"))
+	if b, err := s.peekNRunes(2); err != nil || b != "Łk" {
+		t.Errorf("peekNRunes(2) = %q, %v, want Łk", b, err)
+	}
+	if b, err := s.peekNRunes(3); err == nil || b != "Łk" {
+		t.Errorf("peekNRunes(3) = %q, %v, want Łk with error", b, err)
+	}
+}
@@ -0,0 +1,98 @@
+package bible
+
+import (
+	"sort"
+	"strings"
+)
+
+// Parallel is passage of reference in several translations,
+// verses are aligned by label.
+type Parallel struct {
+	Header string
+	// Translation codes in order of Texts of every verse.
+	Translations []string
+	Verses       []ParallelVerse
+}
+
+// ParallelVerse holds texts of single verse, text is empty
+// when translation lacks the verse.
+type ParallelVerse struct {
+	// Label in numbering of default translation.
+	Label Label
+	// Inline verse number, e.g. "1,1" or "2".
+	Number string
+	Texts  []string
+}
+
+// GetParallel returns passage of reference in translations, verses
+// which exist in any of them are included.
+func (s *service) GetParallel(ref string, translations []string) (*Parallel, error) {
+	verses, err := NewParser(strings.NewReader(ref), s).Parse()
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]string, 0, len(verses))
+	for _, verse := range verses {
+		header, err := s.VerseHeader(verse)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	texts := make([]*text, 0, len(translations))
+	for _, code := range translations {
+		t, err := s.translation(code)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, t)
+	}
+
+	p := &Parallel{Header: strings.Join(headers, "; ")}
+	rows := make(map[Label]*ParallelVerse)
+	for i, t := range texts {
+		p.Translations = append(p.Translations, t.code)
+		for _, verse := range verses {
+			start, end, err := s.locate(verse, t)
+			if err == ErrVerseNotInTranslation {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for idx := start; idx <= end; idx++ {
+				// Align verses in reference numbering.
+				l := t.versification.ToReference(t.store.labels[idx])
+				row, ok := rows[l]
+				if !ok {
+					row = &ParallelVerse{Label: l, Number: s.inlineVerseNumber(l), Texts: make([]string, len(texts))}
+					rows[l] = row
+				}
+				row.Texts[i] = t.store.texts[idx]
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return nil, ErrVerseNotInTranslation
+	}
+
+	for _, row := range rows {
+		p.Verses = append(p.Verses, *row)
+	}
+	// Labels of parts don't sort with whole verses, e.g. "001001010"
+	// goes before "00100101a", so verses keep order of default text.
+	order := func(l Label) int {
+		if idx, ok := s.store.find(l, false); ok {
+			return idx
+		}
+		return s.store.maxIndex() + 1
+	}
+	sort.Slice(p.Verses, func(i, j int) bool {
+		a, b := order(p.Verses[i].Label), order(p.Verses[j].Label)
+		if a != b {
+			return a < b
+		}
+		return p.Verses[i].Label < p.Verses[j].Label
+	})
+	return p, nil
+}
@@ -0,0 +1,86 @@
+package bible
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func Test_service_GetParallel(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name         string
+		ref          string
+		translations []string
+		want         *Parallel
+		wantErr      error
+	}{
+		{"verse missing on the right", "rodz 1,2-3", []string{"bt", "bw"}, &Parallel{
+			Header:       "rodz 1,2-3",
+			Translations: []string{"bt", "bw"},
+			Verses: []ParallelVerse{
+				{"001001002", "2", []string{
+					"Ziemia zaś była bezładem i pustkowiem: ciemność była nad powierzchnią bezmiaru wód, a Duch Boży unosił się nad wodami.",
+					"A ziemia była pustkowiem i chaosem; ciemność była nad otchłanią, a Duch Boży unosił się nad powierzchnią wód.",
+				}},
+				{"001001003", "3", []string{
+					"Wtedy Bóg rzekł: «Niechaj się stanie światłość!» I stała się światłość.",
+					"",
+				}},
+			},
+		}, nil},
+		{"verse missing on the left", "rodz 2", []string{"", "bw"}, &Parallel{
+			Header:       "rodz 2,1-2",
+			Translations: []string{"bt", "bw"},
+			Verses: []ParallelVerse{
+				{"001002001", "2,1", []string{
+					"W ten sposób zostały ukończone niebo i ziemia oraz wszystkie jej zastępy [stworzeń].",
+					"Tak zostały ukończone niebo i ziemia oraz wszystkie ich zastępy.",
+				}},
+				{"001002002", "2", []string{
+					"A gdy Bóg ukończył w dniu szóstym swe dzieło, nad którym pracował, odpoczął dnia siódmego po całym swym trudzie.",
+					"I ukończył Bóg dnia siódmego dzieło swoje, które uczynił, i odpoczął dnia siódmego od wszelkiego dzieła swego, które uczynił.",
+				}},
+				{"001002003", "3", []string{
+					"",
+					"I pobłogosławił Bóg dzień siódmy, i poświęcił go, gdyż w nim odpoczął od wszelkiego dzieła swego, którego Bóg dokonał w stworzeniu.",
+				}},
+			},
+		}, nil},
+		{"missing everywhere", "rodz 1,3", []string{"bw"}, nil, ErrVerseNotInTranslation},
+		{"unknown translation", "rodz 1,1", []string{"bt", "kjv"}, nil, ErrUnknownTranslation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetParallel(tt.ref, tt.translations)
+			if err != tt.wantErr {
+				t.Fatalf("GetParallel() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetParallel() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_service_GetParallel_parts(t *testing.T) {
+	texts := []TextSource{{Code: "sp", Path: "testdata/split.txt"}}
+	s, err := New("../data/ksiegi.txt", texts, "testdata/plan.csv", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := s.GetParallel("rodz 1", []string{"sp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Label
+	for _, v := range p.Verses {
+		got = append(got, v.Label)
+	}
+	if want := []Label{"001001001", "00100102a", "00100102b", "001001020"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetParallel() verses = %v, want %v", got, want)
+	}
+}
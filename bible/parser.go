@@ -1,37 +1,69 @@
 package bible
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
-//go:generate stringer -type=State
-type State int
-
-const (
-	S_START State = iota
-	S_BOOK
-	S_CHAPTER
-	S_VERSE
-	S_NEXT_NUM
-	S_NEXT_VERSE
-	S_END
+// Highest chapter or verse number which fits into label token.
+const maxNumber = 999
+
+var (
+	ErrChapterOutOfRange = errors.New("chapter does not exist")
+	ErrVerseOutOfRange   = errors.New("verse does not exist")
+	ErrInvertedRange     = errors.New("range ends before it starts")
 )
 
+// ParseError points at token of reference which couldn't be parsed.
+type ParseError struct {
+	// Position of token in runes.
+	Pos int
+	// Literal of token, empty at end of input.
+	Found string
+	// Expected token of syntax error, e.g. "verse".
+	Expected string
+	// Cause of error when token is well formed: *BookError,
+	// ErrChapterOutOfRange, ErrVerseOutOfRange or ErrInvertedRange.
+	Err error
+	// Last chapter of book or last verse of chapter when out of range.
+	Last int
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("reference error at %d: %q: %s", e.Pos, e.Found, e.Err)
+	case e.Found == "":
+		return fmt.Sprintf("reference error at %d: expected %s", e.Pos, e.Expected)
+	}
+	return fmt.Sprintf("reference error at %d: expected %s, got %q", e.Pos, e.Expected, e.Found)
+}
+
+// Parser reads list of references, e.g. "J 3,16; 4,1" or "Mt 5,3-4.7".
+//
+//	refs    = ref { ";" ref }
+//	ref     = [ BOOK ] NUM ( "," verses | "-" end | )
+//	verses  = verse { "." verse }
+//	verse   = NUM [ "-" end ]
+//	end     = [ BOOK ] NUM [ "," NUM ]
+//
+// Reference without book continues with book where previous one ends.
+// Number followed by comma after dash is chapter, so "1,1-2,3" ends
+// at 2,3, range may end in other book, e.g. "rodz 50,1 - wj 1,3".
+// Verse number may end with letter of its part, e.g. "3,16a", and
+// verse may be separated from chapter with colon, e.g. "J 3:16".
 type Parser struct {
 	s   *Scanner
 	buf struct {
 		tok Token  // last read token
 		lit string // last read literal
+		pos int    // position of last read token
 		n   int    // buffer size (max = 1)
 	}
-	state     State
-	lastState State
-
-	versePointer int
-
-	// The list of references that were discovered.
-	verses []string
 
 	bsvc Service
 }
@@ -48,10 +80,11 @@ func (p *Parser) scan() (tok Token, lit string) {
 	}
 
 	// Otherwise read next token from scanner.
+	pos := p.s.pos
 	tok, lit = p.s.Scan()
 
 	// Save it to the buffer in case we unscan later.
-	p.buf.tok, p.buf.lit = tok, lit
+	p.buf.tok, p.buf.lit, p.buf.pos = tok, lit, pos
 
 	return
 }
@@ -67,12 +100,6 @@ func (p *Parser) scanIgnoreWhitespace() (tok Token, lit string) {
 	return
 }
 
-func (p *Parser) updateVerse(idxNum string) {
-	v := p.verses[p.versePointer]
-	v += p.expandWithZeros(idxNum)
-	p.verses[p.versePointer] = v
-}
-
 func (p *Parser) formatedBookNumber(bookName string) (string, error) {
 	bookNum, err := p.bsvc.GetBookNumber(bookName)
 	if err != nil {
@@ -87,11 +114,8 @@ func (p *Parser) formatedBookNumber(bookName string) (string, error) {
 	return bookNumString, nil
 }
 
-func (p *Parser) nextVerse() {
-	p.verses = append(p.verses, "")
-	p.versePointer++
-}
-
+// expandWithZeros pads number to 3 characters, including letter of
+// verse part, so "1a" becomes "01a".
 func (p *Parser) expandWithZeros(lit string) string {
 	switch len(lit) {
 	case 0:
@@ -105,101 +129,293 @@ func (p *Parser) expandWithZeros(lit string) string {
 	}
 }
 
-func (p *Parser) Parse() (*Verse, error) {
-	p.versePointer = 0
-
-	p.state = S_START
-	p.lastState = S_START
-
-	var tok Token
-	var lit string
-	// Preserve book.
-	var currentBookIdx string
-	var currentChapterIdx string
-
+// Parse returns verse spans of all references in order, errors
+// are reported with *ParseError.
+func (p *Parser) Parse() ([]*Verse, error) {
+	var (
+		verses []*Verse
+		// Chapter which reference without book continues.
+		current point
+	)
 	for {
-		//fmt.Println(p.state, tok)
-		switch {
-		case p.state == S_START:
-			// Add empty string so it exist.
-			p.verses = append(p.verses, "")
-		case p.state == S_BOOK:
-			// Append book number to index and preserve book
-			// for future verses.
-			bookNum, err := p.formatedBookNumber(lit)
+		tok, lit := p.scanIgnoreWhitespace()
+		switch tok {
+		case EOF:
+			if len(verses) == 0 {
+				return nil, p.expected("reference")
+			}
+			return verses, nil
+		case SEMICOLON:
+			// Empty reference.
+			continue
+		case BOOK:
+			book, err := p.book(lit)
 			if err != nil {
 				return nil, err
 			}
-			p.updateVerse(bookNum)
-			currentBookIdx = bookNum
-		case p.state == S_NEXT_NUM && p.lastState == S_VERSE && tok == DASH:
-			p.nextVerse()
-			_, lit = p.scanIgnoreWhitespace()
-			p.updateVerse(currentBookIdx)
-			toknext, _ := p.scanIgnoreWhitespace()
-			if toknext == COMMA {
-				p.unscan()
-				p.updateVerse(lit) // add chapter index
-			} else if toknext == EOF {
-				p.updateVerse(currentChapterIdx)
-				p.updateVerse(lit) // add verse index
-			}
-		case p.state == S_CHAPTER && tok == DASH:
-			// Look ahead to get next chapter (next verse)
-			p.nextVerse()
-			_, lit = p.scanIgnoreWhitespace()
-			p.updateVerse(currentBookIdx)
-			p.updateVerse(lit) // add chapter index
-		case p.state == S_CHAPTER:
-			p.updateVerse(lit) // add chapter index
-			currentChapterIdx = lit
-		case p.state == S_VERSE:
-			if tok != NEXT_NUM {
-				_, lit = p.scanIgnoreWhitespace()
-			}
-			p.updateVerse(lit) // add verse index
-		case p.state == S_END:
-			// We expect 1 or 2 verses.
-			if len(p.verses) == 1 {
-				return p.bsvc.NewVerseFromSingleLabel(Label(p.verses[0]))
-			}
-			if len(p.verses) >= 2 {
-				return p.bsvc.NewVerseFromDualLabel(Label(p.verses[0]), Label(p.verses[1]))
+			current.book = book
+			tok, lit = p.scanIgnoreWhitespace()
+		default:
+			if current.book == "" {
+				return nil, p.expected("book")
 			}
-			return nil, fmt.Errorf("parser error, nothing to create")
 		}
 
-		tok, lit = p.scanIgnoreWhitespace()
+		spans, err := p.parseReference(&current, tok)
+		if err != nil {
+			return nil, err
+		}
+		verses = append(verses, spans...)
+
+		if tok, _ := p.scanIgnoreWhitespace(); tok != EOF && tok != SEMICOLON {
+			return nil, p.expected(`";"`)
+		}
+	}
+}
 
-		// Find next state and buffer last one.
-		p.lastState = p.state
-		p.state = p.NextState(tok, lit)
+// number is numeric token along with its position.
+type number struct {
+	lit string
+	pos int
+}
 
+// point is chapter or verse which reference starts or ends at.
+type point struct {
+	book    string
+	chapter number
+	// Nil for whole chapter.
+	verse *number
+}
+
+func (p *Parser) chapterLabel(pt point) Label {
+	return Label(pt.book + p.expandWithZeros(pt.chapter.lit))
+}
+
+func (p *Parser) label(pt point) Label {
+	if pt.verse == nil {
+		return p.chapterLabel(pt)
 	}
+	return p.chapterLabel(pt) + Label(p.expandWithZeros(pt.verse.lit))
 }
 
-func (p *Parser) NextState(tok Token, lit string) State {
+// parseReference reads chapter starting with tok and what follows it,
+// current is moved to chapter where reference ends.
+func (p *Parser) parseReference(current *point, tok Token) ([]*Verse, error) {
+	if tok != NEXT_NUM {
+		return nil, p.expected("chapter")
+	}
+	chapter, err := p.chapter(number{p.buf.lit, p.buf.pos})
+	if err != nil {
+		return nil, err
+	}
+	current.chapter, current.verse = chapter, nil
+	start := *current
 
-	switch {
-	// Book at start.
-	case p.state == S_START && tok == BOOK:
-		return S_BOOK
-	// After BOOK we expect chapter directly.
-	case p.state == S_BOOK:
-		return S_CHAPTER
-	// After chapter if COMMA we will have verse.
-	case p.state == S_CHAPTER && tok == COMMA:
-		return S_VERSE
-	// If there was chapter last time and DASH pop then we have next chapter.
-	case p.state == S_CHAPTER && tok == DASH:
-		return S_CHAPTER
-	// If there was verse and DASH pop then we expect CHAPTER OR VERSE
-	case p.state == S_VERSE && tok == DASH:
-		return S_NEXT_NUM
-	case p.state == S_NEXT_NUM && tok == COMMA:
-		return S_VERSE
-	case tok == EOF:
-		return S_END
-	}
-	return S_END
+	switch tok, _ := p.scanIgnoreWhitespace(); tok {
+	case COMMA:
+		return p.parseVerses(current)
+	case DASH:
+		// Chapter range, optionally ending at verse.
+		end, err := p.parseRangeEnd(start)
+		if err != nil {
+			return nil, err
+		}
+		v, err := p.newRange(start, end)
+		if err != nil {
+			return nil, err
+		}
+		current.book, current.chapter = end.book, end.chapter
+		return []*Verse{v}, nil
+	default:
+		p.unscan()
+		v, err := p.newVerse(start)
+		if err != nil {
+			return nil, err
+		}
+		return []*Verse{v}, nil
+	}
+}
+
+// parseVerses reads dot separated verses or verse ranges of chapter.
+func (p *Parser) parseVerses(chapter *point) ([]*Verse, error) {
+	var verses []*Verse
+	for {
+		v, err := p.parseVerse(chapter)
+		if err != nil {
+			return nil, err
+		}
+		verses = append(verses, v)
+
+		if tok, _ := p.scanIgnoreWhitespace(); tok != DOT {
+			p.unscan()
+			return verses, nil
+		}
+	}
+}
+
+// parseVerse reads verse or verse range. Range may end in other
+// chapter, which following verses belong to.
+func (p *Parser) parseVerse(chapter *point) (*Verse, error) {
+	n, err := p.expectNumber("verse")
+	if err != nil {
+		return nil, err
+	}
+	start := *chapter
+	start.verse = &n
+
+	if tok, _ := p.scanIgnoreWhitespace(); tok != DASH {
+		p.unscan()
+		return p.newVerse(start)
+	}
+	end, err := p.parseRangeEnd(start)
+	if err != nil {
+		return nil, err
+	}
+	chapter.book, chapter.chapter = end.book, end.chapter
+	return p.newRange(start, end)
+}
+
+// parseRangeEnd reads end of range which starts at from. Range ends in
+// other book when it's named, e.g. "wj 1,3" of "rodz 50,1 - wj 1,3".
+// Number followed by comma is chapter, single number is verse of
+// chapter where range starts, or chapter if range starts at chapter.
+func (p *Parser) parseRangeEnd(from point) (point, error) {
+	end := point{book: from.book, chapter: from.chapter}
+	named := false
+	if tok, lit := p.scanIgnoreWhitespace(); tok == BOOK {
+		book, err := p.book(lit)
+		if err != nil {
+			return end, err
+		}
+		end.book, named = book, true
+	} else {
+		p.unscan()
+	}
+
+	what := "chapter"
+	if from.verse != nil && !named {
+		what = "verse"
+	}
+	n, err := p.expectNumber(what)
+	if err != nil {
+		return end, err
+	}
+	if tok, _ := p.scanIgnoreWhitespace(); tok == COMMA {
+		verse, err := p.expectNumber("verse")
+		if err != nil {
+			return end, err
+		}
+		if end.chapter, err = p.chapter(n); err != nil {
+			return end, err
+		}
+		end.verse = &verse
+		return end, nil
+	}
+	p.unscan()
+	if what == "verse" {
+		end.verse = &n
+		return end, nil
+	}
+	if end.chapter, err = p.chapter(n); err != nil {
+		return end, err
+	}
+	return end, nil
+}
+
+func (p *Parser) newVerse(pt point) (*Verse, error) {
+	if err := p.check(pt); err != nil {
+		return nil, err
+	}
+	return p.bsvc.NewVerseFromSingleLabel(p.label(pt))
+}
+
+func (p *Parser) newRange(start, end point) (*Verse, error) {
+	if err := p.check(start); err != nil {
+		return nil, err
+	}
+	if err := p.check(end); err != nil {
+		return nil, err
+	}
+	v, err := p.bsvc.NewVerseFromDualLabel(p.label(start), p.label(end))
+	if err != nil {
+		return nil, err
+	}
+	if v.End() < v.Start() {
+		n := end.chapter
+		if end.verse != nil {
+			n = *end.verse
+		}
+		return nil, &ParseError{Pos: n.pos, Found: n.lit, Err: ErrInvertedRange}
+	}
+	return v, nil
+}
+
+// check reports chapter or verse of pt which doesn't exist.
+func (p *Parser) check(pt point) error {
+	chapter := p.chapterLabel(pt)
+	if _, err := p.bsvc.GetIndexFromLabel(chapter); err != nil {
+		return &ParseError{
+			Pos:   pt.chapter.pos,
+			Found: pt.chapter.lit,
+			Err:   ErrChapterOutOfRange,
+			Last:  p.last(Label(pt.book)),
+		}
+	}
+	if pt.verse == nil {
+		return nil
+	}
+	if _, err := p.bsvc.GetIndexFromLabel(p.label(pt)); err != nil {
+		return &ParseError{
+			Pos:   pt.verse.pos,
+			Found: pt.verse.lit,
+			Err:   ErrVerseOutOfRange,
+			Last:  p.last(chapter),
+		}
+	}
+	return nil
+}
+
+// last returns highest chapter of book or verse of chapter label.
+func (p *Parser) last(prefix Label) int {
+	for n := maxNumber; n > 0; n-- {
+		if _, err := p.bsvc.GetIndexFromLabel(prefix + Label(p.expandWithZeros(strconv.Itoa(n)))); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// book returns book number of name read as last token.
+func (p *Parser) book(name string) (string, error) {
+	book, err := p.formatedBookNumber(name)
+	if err != nil {
+		return "", &ParseError{Pos: p.buf.pos, Found: name, Err: err}
+	}
+	return book, nil
+}
+
+// chapter checks that number is chapter, unlike verses chapters have
+// no parts.
+func (p *Parser) chapter(n number) (number, error) {
+	if strings.IndexFunc(n.lit, unicode.IsLetter) >= 0 {
+		return n, &ParseError{Pos: n.pos, Found: n.lit, Expected: "chapter"}
+	}
+	return n, nil
+}
+
+func (p *Parser) expectNumber(what string) (number, error) {
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok != NEXT_NUM {
+		return number{}, p.expected(what)
+	}
+	return number{lit, p.buf.pos}, nil
+}
+
+// expected returns syntax error at last read token.
+func (p *Parser) expected(what string) error {
+	e := &ParseError{Pos: p.buf.pos, Expected: what}
+	if p.buf.tok != EOF {
+		e.Found = p.buf.lit
+	}
+	return e
 }
@@ -12,7 +12,7 @@ import (
 func TestParser_Parse(t *testing.T) {
 	logger := log.NewNopLogger()
 
-	s, err := New("", "", "", logger)
+	s, err := New("", nil, "", logger)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -20,43 +20,43 @@ func TestParser_Parse(t *testing.T) {
 	tests := []struct {
 		name    string
 		buf     io.Reader
-		want    *Verse
+		want    []*Verse
 		wantErr bool
 	}{
 		{
 			"single",
 			strings.NewReader("1kor 1,1"),
-			&Verse{start: 28701, end: 0},
+			[]*Verse{{start: 28701, end: 0}},
 			false,
 		},
 		{
 			"single1",
 			strings.NewReader("1kor 2,1"),
-			&Verse{start: 28732, end: 0},
+			[]*Verse{{start: 28732, end: 0}},
 			false,
 		},
 		{
 			"range",
 			strings.NewReader("1kor 1,1-31"),
-			&Verse{start: 28701, end: 28731},
+			[]*Verse{{start: 28701, end: 28731}},
 			false,
 		},
 		{
 			"chapter_range",
 			strings.NewReader("1kor 1"),
-			&Verse{start: 28701, end: 28731},
+			[]*Verse{{start: 28701, end: 28731}},
 			false,
 		},
 		{
 			"range1",
 			strings.NewReader("1kor 1,1-2"),
-			&Verse{start: 28701, end: 28702},
+			[]*Verse{{start: 28701, end: 28702}},
 			false,
 		},
 		{
 			"range2",
 			strings.NewReader("1kor 1,1-2,1"),
-			&Verse{start: 28701, end: 28732},
+			[]*Verse{{start: 28701, end: 28732}},
 			false,
 		},
 	}
@@ -74,3 +74,166 @@ func TestParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ParseList(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    []*Verse
+		wantErr bool
+	}{
+		{"semicolon", "rodz 1,1; 2,1", []*Verse{{start: 0}, {start: 3}}, false},
+		{"other book", "rodz 1,2; wj 1,1", []*Verse{{start: 1}, {start: 5}}, false},
+		{"dot", "rodz 1,1.3", []*Verse{{start: 0}, {start: 2}}, false},
+		{"range and dot", "rodz 1,1-2.3", []*Verse{{start: 0, end: 1}, {start: 2}}, false},
+		{"cross chapter", "rodz 1,2-2,1", []*Verse{{start: 1, end: 3}}, false},
+		{"cross chapter and dot", "rodz 1,3-2,1.2", []*Verse{{start: 2, end: 3}, {start: 4}}, false},
+		{"chapter range", "rodz 1-2", []*Verse{{start: 0, end: 4}}, false},
+		{"trailing semicolon", "rodz 1,1;", []*Verse{{start: 0}}, false},
+		{"colon", "rodz 1:3", []*Verse{{start: 2}}, false},
+		{"cross book", "rodz 2,2 - wj 1,1", []*Verse{{start: 4, end: 5}}, false},
+		{"cross book chapters", "rodz 2 - wj 1", []*Verse{{start: 3, end: 6}}, false},
+		{"list after cross book", "rodz 1,1 - wj 1,1; 1,2", []*Verse{{start: 0, end: 5}, {start: 6}}, false},
+		{"dot after cross book", "rodz 2,2 - wj 1,1.2", []*Verse{{start: 4, end: 5}, {start: 6}}, false},
+		{"inverted cross book", "wj 1,1 - rodz 1,1", nil, true},
+		{"cross book without chapter", "rodz 1,1 - wj", nil, true},
+		{"missing book", "1,1", nil, true},
+		{"missing chapter", "rodz ,1", nil, true},
+		{"missing verse", "rodz 1,1.", nil, true},
+		{"garbage after reference", "rodz 1,1 abc", nil, true},
+		{"empty", ";", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(strings.NewReader(tt.ref), s).Parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parser.Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseNumberedBooks(t *testing.T) {
+	s := newCanonService(t)
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    []*Verse
+		wantErr bool
+	}{
+		{"numbered book", "1kor 16,2", []*Verse{{start: 21}}, false},
+		{"numbered book with space", "1 kor 16,1-2", []*Verse{{start: 20, end: 21}}, false},
+		{"cross book to numbered", "powt 1,2 - 1s 1,1", []*Verse{{start: 15, end: 16}}, false},
+		{"cross numbered books", "1kor 16,1 - 2kor 1,2", []*Verse{{start: 20, end: 23}}, false},
+		{"cross numbered books chapters", "1kor 16 - 2kor 1", []*Verse{{start: 20, end: 23}}, false},
+		{"cross numbered books without spaces", "1s 1,2-2s 1,1", []*Verse{{start: 17, end: 18}}, false},
+		{"cross numbered books with space in name", "1 sam 1 - 2 sam 1", []*Verse{{start: 16, end: 19}}, false},
+		{"cross numbered books and list", "1kor 16,1 - 2kor 1,1; 1,2", []*Verse{{start: 20, end: 22}, {start: 23}}, false},
+		{"inverted cross numbered books", "2kor 1,1 - 1kor 16,1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(strings.NewReader(tt.ref), s).Parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parser.Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseParts(t *testing.T) {
+	texts := []TextSource{{Code: "pt", Path: "testdata/pt.txt"}}
+	s, err := New("../data/ksiegi.txt", texts, "testdata/plan.csv", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    []*Verse
+		wantErr bool
+	}{
+		{"part", "rodz 1,2a", []*Verse{{start: 1}}, false},
+		{"upper case part", "rodz 1,2B", []*Verse{{start: 2}}, false},
+		{"whole verse of parts", "rodz 1,2", []*Verse{{start: 1, end: 2}}, false},
+		{"range from part", "rodz 1,2b-3", []*Verse{{start: 2, end: 3}}, false},
+		{"range to part", "rodz 1,1-2a", []*Verse{{start: 0, end: 1}}, false},
+		{"range to verse of parts", "rodz 1,1-2", []*Verse{{start: 0, end: 2}}, false},
+		{"range to part with spaces", "rodz 1,1 - 2a", []*Verse{{start: 0, end: 1}}, false},
+		{"range to part and list", "rodz 1,1-2a; 2,1", []*Verse{{start: 0, end: 1}, {start: 4}}, false},
+		{"part of whole verse", "rodz 1,1a", []*Verse{{start: 0}}, false},
+		{"parts list", "rodz 1,2a.3", []*Verse{{start: 1}, {start: 3}}, false},
+		{"missing part", "rodz 1,2c", nil, true},
+		{"chapter part", "rodz 1a,1", nil, true},
+		{"chapter range part", "rodz 1-2a", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(strings.NewReader(tt.ref), s).Parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parser.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parser.Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseErrors(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name string
+		ref  string
+		want *ParseError
+	}{
+		{"unknown book", "rodx 1,1", &ParseError{Pos: 0, Found: "rodx", Err: &BookError{Name: "rodx", Suggestions: []string{"rodz"}}}},
+		{"chapter out of range", "rodz 3,1", &ParseError{Pos: 5, Found: "3", Err: ErrChapterOutOfRange, Last: 2}},
+		{"chapter out of range in unicode", "łk 1", &ParseError{Pos: 3, Found: "1", Err: ErrChapterOutOfRange}},
+		{"verse out of range", "rodz 1,4", &ParseError{Pos: 7, Found: "4", Err: ErrVerseOutOfRange, Last: 3}},
+		{"range end out of range", "rodz 1,2-2,5", &ParseError{Pos: 11, Found: "5", Err: ErrVerseOutOfRange, Last: 2}},
+		{"inverted range", "rodz 1,3-1", &ParseError{Pos: 9, Found: "1", Err: ErrInvertedRange}},
+		{"inverted chapter range", "rodz 2-1", &ParseError{Pos: 7, Found: "1", Err: ErrInvertedRange}},
+		{"missing verse", "rodz 1,", &ParseError{Pos: 7, Expected: "verse"}},
+		{"missing chapter", "rodz x", &ParseError{Pos: 5, Found: "x", Expected: "chapter"}},
+		{"chapter part", "rodz 1a,1", &ParseError{Pos: 5, Found: "1a", Expected: "chapter"}},
+		{"missing book", "1,1", &ParseError{Pos: 0, Found: "1", Expected: "book"}},
+		{"trailing garbage", "rodz 1,1 abc", &ParseError{Pos: 9, Found: "abc", Expected: `";"`}},
+		{"empty", "", &ParseError{Pos: 0, Expected: "reference"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(strings.NewReader(tt.ref), s).Parse()
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("Parser.Parse() error = %#v, want %#v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError_Error(t *testing.T) {
+	tests := []struct {
+		err  *ParseError
+		want string
+	}{
+		{&ParseError{Pos: 7, Found: "4", Err: ErrVerseOutOfRange, Last: 3}, `reference error at 7: "4": verse does not exist`},
+		{&ParseError{Pos: 5, Found: "x", Expected: "chapter"}, `reference error at 5: expected chapter, got "x"`},
+		{&ParseError{Pos: 7, Expected: "verse"}, `reference error at 7: expected verse`},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
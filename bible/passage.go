@@ -0,0 +1,106 @@
+package bible
+
+import (
+	"strings"
+)
+
+// Passage is text of reference prepared for rendering.
+type Passage struct {
+	// Human readable reference, e.g. "rodz 1,1-3".
+	Header string
+	// Translation code of texts.
+	Translation string
+	// Texts of verses without numbers.
+	Verses []string
+	// Header and verses with inline numbers, same as GetTextByReference
+	// of single span.
+	Text string
+	// Reference of chapter where passage starts.
+	Chapter string
+	// Reference of following chapter, empty at the end of text.
+	NextChapter string
+	// Set when passage covers whole chapter.
+	WholeChapter bool
+}
+
+// GetPassages returns passage of every span of reference in
+// translation, empty translation means default one.
+func (s *service) GetPassages(translation, ref string) ([]*Passage, error) {
+	verses, err := NewParser(strings.NewReader(ref), s).Parse()
+	if err != nil {
+		return nil, err
+	}
+	passages := make([]*Passage, 0, len(verses))
+	for _, verse := range verses {
+		p, err := s.passage(translation, verse)
+		if err != nil {
+			return nil, err
+		}
+		passages = append(passages, p)
+	}
+	return passages, nil
+}
+
+func (s *service) passage(translation string, verse *Verse) (*Passage, error) {
+	t, err := s.translation(translation)
+	if err != nil {
+		return nil, err
+	}
+	text, err := s.GetVerseText(t.code, verse)
+	if err != nil {
+		return nil, err
+	}
+	header, err := s.VerseHeader(verse)
+	if err != nil {
+		return nil, err
+	}
+	p := &Passage{
+		Header:      header,
+		Translation: t.code,
+		Text:        strings.Join(text, " "),
+	}
+
+	start, last, err := s.locate(verse, t)
+	if err != nil {
+		return nil, err
+	}
+	for i := start; i <= last; i++ {
+		p.Verses = append(p.Verses, t.store.texts[i])
+	}
+
+	end := verse.End()
+	if verse.IsSingle() {
+		end = verse.Start()
+	}
+
+	chapterStart, err := s.GetChapterStartIndex(verse.Start())
+	if err != nil {
+		return nil, err
+	}
+	chapterEnd := s.GetChapterEndIndex(verse.Start())
+	p.WholeChapter = verse.Start() == chapterStart && end >= chapterEnd
+
+	if p.Chapter, err = s.chapterReference(verse.Start()); err != nil {
+		return nil, err
+	}
+	if chapterEnd < s.store.maxIndex() {
+		if p.NextChapter, err = s.chapterReference(chapterEnd + 1); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// chapterReference returns parsable reference of chapter with verse
+// at index, e.g. "rodz 2".
+func (s *service) chapterReference(idx int) (string, error) {
+	label, err := s.GetLabel(idx)
+	if err != nil {
+		return "", err
+	}
+	book, err := s.getBookFromLabel(label)
+	if err != nil {
+		return "", err
+	}
+	return book + " " + s.getChapterFromLabel(label), nil
+}
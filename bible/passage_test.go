@@ -0,0 +1,83 @@
+package bible
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func newTestService(t *testing.T) Service {
+	texts := []TextSource{{Code: "bt", Path: "testdata/bt.txt"}, {Code: "bw", Path: "testdata/bw.txt"}}
+	s, err := New("../data/ksiegi.txt", texts, "testdata/plan.csv", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func Test_service_GetPassages(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    *Passage
+		wantErr bool
+	}{
+		{"single verse", "rodz 1,3", &Passage{
+			Header:      "rodz 1,3",
+			Translation: "bt",
+			Verses:      []string{"Wtedy Bóg rzekł: «Niechaj się stanie światłość!» I stała się światłość."},
+			Text:        "rodz 1,3\n 3 Wtedy Bóg rzekł: «Niechaj się stanie światłość!» I stała się światłość.",
+			Chapter:     "rodz 1",
+			NextChapter: "rodz 2",
+		}, false},
+		{"range", "rodz 1,1-2", &Passage{
+			Header:      "rodz 1,1-2",
+			Translation: "bt",
+			Verses: []string{
+				"Na początku Bóg stworzył niebo i ziemię.",
+				"Ziemia zaś była bezładem i pustkowiem: ciemność była nad powierzchnią bezmiaru wód, a Duch Boży unosił się nad wodami.",
+			},
+			Text:        "rodz 1,1-2\n 1,1 Na początku Bóg stworzył niebo i ziemię. 2 Ziemia zaś była bezładem i pustkowiem: ciemność była nad powierzchnią bezmiaru wód, a Duch Boży unosił się nad wodami.",
+			Chapter:     "rodz 1",
+			NextChapter: "rodz 2",
+		}, false},
+		{"whole chapter followed by next book", "rodz 2", &Passage{
+			Header:      "rodz 2,1-2",
+			Translation: "bt",
+			Verses: []string{
+				"W ten sposób zostały ukończone niebo i ziemia oraz wszystkie jej zastępy [stworzeń].",
+				"A gdy Bóg ukończył w dniu szóstym swe dzieło, nad którym pracował, odpoczął dnia siódmego po całym swym trudzie.",
+			},
+			Text:         "rodz 2,1-2\n 2,1 W ten sposób zostały ukończone niebo i ziemia oraz wszystkie jej zastępy [stworzeń]. 2 A gdy Bóg ukończył w dniu szóstym swe dzieło, nad którym pracował, odpoczął dnia siódmego po całym swym trudzie.",
+			Chapter:      "rodz 2",
+			NextChapter:  "wy 1",
+			WholeChapter: true,
+		}, false},
+		{"last chapter", "wj 1,2", &Passage{
+			Header:      "wy 1,2",
+			Translation: "bt",
+			Verses:      []string{"Ruben, Symeon, Lewi i Juda."},
+			Text:        "wy 1,2\n 2 Ruben, Symeon, Lewi i Juda.",
+			Chapter:     "wy 1",
+		}, false},
+		{"unknown book", "abc 1,1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetPassages("", tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetPassages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var want []*Passage
+			if tt.want != nil {
+				want = []*Passage{tt.want}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetPassages() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
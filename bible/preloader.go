@@ -1,12 +1,10 @@
 package bible
 
 import (
-	"bufio"
 	"encoding/csv"
 	"io"
 	"os"
 	"strconv"
-	"strings"
 )
 
 // LoadBookIndex - return book maps, find names by book number, find number by book name.
@@ -87,47 +85,3 @@ func LoadPlanReferences(path string) (map[int][]string, error) {
 
 	return planRef, nil
 }
-
-type LoadTextResponse struct {
-	IndexMap map[Label]int
-	LabelMap map[int]Label
-	TextMap  map[int]string
-	MaxIndex int
-}
-
-func LoadText(path string) (*LoadTextResponse, error) {
-
-	if path == "" {
-		path = "../data/bt.txt"
-	}
-
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	sc := bufio.NewScanner(f)
-
-	var idx int
-
-	idxMap := make(map[Label]int)
-	labelMap := make(map[int]Label)
-	textMap := make(map[int]string)
-
-	for sc.Scan() {
-		t := sc.Text()
-		sp := strings.SplitN(t, " ", 2)
-		idxMap[Label(sp[0])] = idx // save ref for index
-		labelMap[idx] = Label(sp[0])
-		textMap[idx] = sp[1]
-		idx++
-	}
-
-	return &LoadTextResponse{
-		IndexMap: idxMap,
-		LabelMap: labelMap,
-		TextMap:  textMap,
-		MaxIndex: idx - 1,
-	}, nil
-}
@@ -0,0 +1,227 @@
+package bible
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// SearchResult is verse matching query.
+type SearchResult struct {
+	// Label in numbering of default translation.
+	Label Label
+	Text  string
+	Score float64
+}
+
+// foldDiacritics maps Polish letters to ASCII, so "laska" matches "łaska".
+var foldDiacritics = strings.NewReplacer(
+	"ą", "a", "ć", "c", "ę", "e", "ł", "l", "ń", "n",
+	"ó", "o", "ś", "s", "ź", "z", "ż", "z",
+)
+
+// Inflection endings removed by stemming, longest first. Endings are
+// folded, as they're removed after diacritics.
+var suffixes = []string{
+	"owie", "ami", "ach", "ego", "emu", "owi", "ych", "ymi", "ich", "imi",
+	"iem", "iej", "om", "ow", "em", "ie", "ej", "ym", "im", "ia", "ii",
+	"a", "e", "i", "o", "u", "y",
+}
+
+// Stem is never shorter than this.
+const minStem = 3
+
+// normalize lowercases word, folds diacritics and strips inflection.
+func normalize(word string) string {
+	w := foldDiacritics.Replace(strings.ToLower(word))
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(w, suffix) && len(w)-len(suffix) >= minStem {
+			return w[:len(w)-len(suffix)]
+		}
+	}
+	return w
+}
+
+// tokenize splits text into normalized words.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make([]string, 0, len(fields))
+	for _, f := range fields {
+		words = append(words, normalize(f))
+	}
+	return words
+}
+
+// posting lists positions of word in verse.
+type posting struct {
+	idx       int
+	positions []int
+}
+
+// searchIndex is inverted index of translation, postings are
+// ordered by verse index.
+type searchIndex struct {
+	postings map[string][]posting
+	verses   int
+}
+
+func buildIndex(t *text) *searchIndex {
+	si := &searchIndex{postings: make(map[string][]posting), verses: len(t.store.texts)}
+	for idx, verse := range t.store.texts {
+		for pos, word := range tokenize(verse) {
+			list := si.postings[word]
+			if n := len(list); n > 0 && list[n-1].idx == idx {
+				list[n-1].positions = append(list[n-1].positions, pos)
+				continue
+			}
+			si.postings[word] = append(list, posting{idx: idx, positions: []int{pos}})
+		}
+	}
+	return si
+}
+
+// Words of query term, more than one for quoted phrase.
+type queryTerm []string
+
+// parseQuery splits query into alternatives separated by "or" or "|",
+// every alternative requires all of its terms. Quoted words are phrase.
+func parseQuery(query string) [][]queryTerm {
+	var (
+		alternatives [][]queryTerm
+		current      []queryTerm
+	)
+	closeAlternative := func() {
+		if len(current) > 0 {
+			alternatives = append(alternatives, current)
+		}
+		current = nil
+	}
+	for i, part := range strings.Split(query, "\"") {
+		if i%2 == 1 {
+			// Quoted phrase.
+			if words := tokenize(part); len(words) > 0 {
+				current = append(current, words)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(strings.Replace(part, "|", " | ", -1)) {
+			if field == "|" || strings.ToLower(field) == "or" {
+				closeAlternative()
+				continue
+			}
+			for _, word := range tokenize(field) {
+				current = append(current, queryTerm{word})
+			}
+		}
+	}
+	closeAlternative()
+	return alternatives
+}
+
+// Search finds verses of translation matching query, results are ranked
+// by TF-IDF of matched words. At most limit results are returned.
+func (s *service) Search(translation, query string, limit int) ([]SearchResult, error) {
+	t, err := s.translation(translation)
+	if err != nil {
+		return nil, err
+	}
+	alternatives := parseQuery(query)
+	if len(alternatives) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	scores := make(map[int]float64)
+	for _, terms := range alternatives {
+		for idx, score := range t.index.match(terms) {
+			scores[idx] += score
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for idx, score := range scores {
+		results = append(results, SearchResult{
+			Label: t.versification.ToReference(t.store.labels[idx]),
+			Text:  t.store.texts[idx],
+			Score: score,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Label < results[j].Label
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// match returns scores of verses containing all terms.
+func (si *searchIndex) match(terms []queryTerm) map[int]float64 {
+	var scores map[int]float64
+	for _, term := range terms {
+		found := si.matchTerm(term)
+		if scores == nil {
+			scores = found
+			continue
+		}
+		for idx := range scores {
+			if score, ok := found[idx]; ok {
+				scores[idx] += score
+			} else {
+				delete(scores, idx)
+			}
+		}
+	}
+	return scores
+}
+
+// matchTerm returns scores of verses containing word or phrase.
+func (si *searchIndex) matchTerm(term queryTerm) map[int]float64 {
+	scores := make(map[int]float64)
+	positions := make(map[int][]int)
+	for i, word := range term {
+		list := si.postings[word]
+		idf := math.Log(1 + float64(si.verses)/float64(len(list)+1))
+		next := make(map[int][]int)
+		for _, p := range list {
+			if i == 0 {
+				next[p.idx] = p.positions
+			} else if starts := followed(positions[p.idx], p.positions, i); len(starts) > 0 {
+				next[p.idx] = starts
+			} else {
+				continue
+			}
+			scores[p.idx] += float64(len(p.positions)) * idf
+		}
+		positions = next
+	}
+	for idx := range scores {
+		if _, ok := positions[idx]; !ok {
+			delete(scores, idx)
+		}
+	}
+	return scores
+}
+
+// followed returns phrase starts for which word at offset is present.
+func followed(starts, positions []int, offset int) []int {
+	at := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		at[p] = true
+	}
+	var kept []int
+	for _, start := range starts {
+		if at[start+offset] {
+			kept = append(kept, start)
+		}
+	}
+	return kept
+}
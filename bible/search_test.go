@@ -0,0 +1,90 @@
+package bible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_normalize(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"Łaska", "lask"},
+		{"łaską", "lask"},
+		{"laski", "lask"},
+		{"Bóg", "bog"},
+		{"Boga", "bog"},
+		{"ziemię", "ziem"},
+		{"ziemia", "ziem"},
+		{"synów", "syn"},
+		{"i", "i"},
+		{"ma", "ma"},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.word); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func Test_parseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  [][]queryTerm
+	}{
+		{"niebo ziemia", [][]queryTerm{{{"nieb"}, {"ziem"}}}},
+		{"niebo or ziemia", [][]queryTerm{{{"nieb"}}, {{"ziem"}}}},
+		{"niebo|ziemia", [][]queryTerm{{{"nieb"}}, {{"ziem"}}}},
+		{`"niebo i ziemia" bóg`, [][]queryTerm{{{"nieb", "i", "ziem"}, {"bog"}}}},
+		{"or ,. |", nil},
+	}
+	for _, tt := range tests {
+		if got := parseQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func Test_service_Search(t *testing.T) {
+	s := newTestService(t)
+
+	labels := func(results []SearchResult) []Label {
+		var l []Label
+		for _, r := range results {
+			l = append(l, r.Label)
+		}
+		return l
+	}
+
+	tests := []struct {
+		name        string
+		translation string
+		query       string
+		limit       int
+		want        []Label
+		wantErr     error
+	}{
+		{"inflected word", "", "ziemia", 0, []Label{"001001001", "001001002", "001002001"}, nil},
+		{"folded diacritics", "", "swiatlosc", 0, []Label{"001001003"}, nil},
+		{"and", "", "niebo ziemia", 0, []Label{"001001001", "001002001"}, nil},
+		{"phrase", "", `"ziemia oraz"`, 0, []Label{"001002001"}, nil},
+		{"phrase order matters", "", `"oraz ziemia"`, 0, nil, nil},
+		{"or ranked by score", "", "światłość or izraela", 0, []Label{"001001003", "002001001"}, nil},
+		{"limit", "", "ziemia", 1, []Label{"001001001"}, nil},
+		{"other translation", "bw", "pobłogosławił", 0, []Label{"001002003"}, nil},
+		{"empty query", "", " | ", 0, nil, ErrEmptyQuery},
+		{"unknown translation", "kjv", "ziemia", 0, nil, ErrUnknownTranslation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Search(tt.translation, tt.query, tt.limit)
+			if err != tt.wantErr {
+				t.Fatalf("Search() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(labels(got), tt.want) {
+				t.Errorf("Search() = %v, want %v", labels(got), tt.want)
+			}
+		})
+	}
+}
@@ -4,26 +4,41 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/go-kit/kit/log"
 )
 
 type Service interface {
-	GetDay(day int) ([]string, error)
+	// GetDay returns texts of plan day in translation, empty
+	// translation means default one.
+	GetDay(translation string, day int) ([]string, error)
 	GetDayReferences(day int) ([]string, error)
+	// MaxDay returns last day of plan, days are counted from 0.
+	MaxDay() int
 	GetBookNumber(bookName string) (int, error)
 	GetText(idx int) (string, error)
 	GetVerseFromIndex(idx int) (*Verse, error)
 	GetBookNames(int) ([]string, error)
-	GetTextByReference(ref string) (string, error)
+	GetTextByReference(translation, ref string) (string, error)
+	// GetPassages returns passage of every span of reference.
+	GetPassages(translation, ref string) ([]*Passage, error)
+	// GetParallel returns reference in translations aligned by verse.
+	GetParallel(ref string, translations []string) (*Parallel, error)
+	// Search returns verses of translation matching query, best first.
+	Search(translation, query string, limit int) ([]SearchResult, error)
+	VerseHeader(*Verse) (string, error)
+	// Format returns reference of verses in style.
+	Format([]*Verse, Style) (string, error)
 	GetIndexFromLabel(Label) (int, error)
 	GetChapterStartIndex(int) (int, error)
 	GetChapterEndIndex(int) int
 	NewVerseFromSingleLabel(Label) (*Verse, error)
 	NewVerseFromDualLabel(Label, Label) (*Verse, error)
-	GetVerseText(*Verse) ([]string, error)
+	GetVerseText(translation string, verse *Verse) ([]string, error)
 	GetLabel(int) (Label, error)
+	// Translations returns codes of loaded translations.
+	Translations() []string
+	DefaultTranslation() string
 }
 
 var _ Service = (*service)(nil)
@@ -33,28 +48,23 @@ type service struct {
 	bookName map[int][]string
 	// Get book number by book name.
 	bookValue map[string]int
-	// Mapping from label (semantic index) into sequential index.
-	idxMap map[Label]int
-	// Label map - maps index back to label...
-	labelLock sync.Mutex
-	labelMap  map[int]Label
-	// Sequential index to verses.
-	textMap map[int]string
+	// Resolves names with other case, diacritics or prefixes.
+	books *bookIndex
+	// Verses of default translation, which define sequential indexes.
+	store *store
 	// Plan with references only...
 	planRef map[int][]string
-	// Plan mapping from plan day into set of verses.
-	plan map[int][]string
 
-	// Maximum index value (sequential index).
-	maxIndex int
+	// Loaded translations by code. References are resolved with
+	// indexes of default translation and mapped to others by label.
+	texts              map[string]*text
+	defaultTranslation string
 
 	log log.Logger
 }
 
 func (s *service) GetLabel(index int) (Label, error) {
-	s.labelLock.Lock()
-	defer s.labelLock.Unlock()
-	if label, ok := s.labelMap[index]; ok {
+	if label, ok := s.store.labelAt(index); ok {
 		return label, nil
 	}
 	return Label(""), fmt.Errorf("could not find index")
@@ -65,11 +75,16 @@ func (s *service) NewVerseFromSingleLabel(label Label) (*Verse, error) {
 	if err != nil {
 		return nil, err
 	}
+	end := 0
 	if len(label) == 6 { // Handle chapter label.
-		end := s.GetChapterEndIndex(start)
-		return &Verse{start, end}, nil
+		end = s.GetChapterEndIndex(start)
+	} else if parts, ok := s.store.parts[label]; ok { // Whole verse split into parts.
+		end = parts.end
+	}
+	if end == start { // Chapter of single verse or verse of single part.
+		end = 0
 	}
-	return &Verse{start, 0}, nil
+	return &Verse{start, end}, nil
 }
 
 func (s *service) NewVerseFromDualLabel(startLabel, endLabel Label) (*Verse, error) {
@@ -85,11 +100,14 @@ func (s *service) NewVerseFromDualLabel(startLabel, endLabel Label) (*Verse, err
 		end = s.GetChapterEndIndex(end)
 		return &Verse{start, end}, nil
 	}
+	if parts, ok := s.store.parts[endLabel]; ok {
+		end = parts.end
+	}
 	return &Verse{start, end}, nil
 }
 
 func (s *service) GetVerseFromIndex(idx int) (*Verse, error) {
-	if label, ok := s.labelMap[idx]; ok {
+	if label, ok := s.store.labelAt(idx); ok {
 		v, err := s.NewVerseFromSingleLabel(label)
 		if err != nil {
 			return nil, err
@@ -100,10 +118,15 @@ func (s *service) GetVerseFromIndex(idx int) (*Verse, error) {
 }
 
 func (s *service) MaxDay() int {
-	return len(s.plan) - 1
+	return len(s.planRef) - 1
 }
 
-func (s *service) GetDay(day int) ([]string, error) {
+func (s *service) GetDay(translation string, day int) ([]string, error) {
+	t, err := s.translation(translation)
+	if err != nil {
+		return nil, err
+	}
+
 	// Make days to rotate over and over...
 	maxDay := s.MaxDay()
 
@@ -111,18 +134,23 @@ func (s *service) GetDay(day int) ([]string, error) {
 		day %= maxDay
 	}
 
-	verses, ok := s.plan[day]
+	verses, ok := t.plan[day]
 	if !ok {
 		return nil, fmt.Errorf("plan day does not exists")
 	}
 	return verses, nil
 }
 
+// GetBookNumber returns number of book, name is matched regardless of
+// case and diacritics. Unknown name is reported with *BookError.
 func (s *service) GetBookNumber(bookName string) (int, error) {
 	if num, ok := s.bookValue[bookName]; ok {
 		return num, nil
 	}
-	return 0, fmt.Errorf("book does not exist")
+	if s.books == nil {
+		return 0, &BookError{Name: bookName}
+	}
+	return s.books.resolve(bookName)
 }
 
 func (s *service) GetBookNames(bookNumber int) ([]string, error) {
@@ -133,37 +161,40 @@ func (s *service) GetBookNames(bookNumber int) ([]string, error) {
 	return bNames, nil
 }
 
-func (s *service) GetTextByReference(ref string) (string, error) {
+// GetTextByReference returns text of reference, every span of it
+// starts on new line with its own header.
+func (s *service) GetTextByReference(translation, ref string) (string, error) {
 	parser := NewParser(strings.NewReader(ref), s)
 
-	verse, err := parser.Parse()
+	verses, err := parser.Parse()
 	if err != nil {
 		return "", err
 	}
 
-	t, err := s.GetVerseText(verse)
-	if err != nil {
-		return "", err
+	spans := make([]string, 0, len(verses))
+	for _, verse := range verses {
+		t, err := s.GetVerseText(translation, verse)
+		if err != nil {
+			return "", err
+		}
+		spans = append(spans, strings.Join(t, " "))
 	}
 
-	return strings.Join(t, " "), nil
+	return strings.Join(spans, "\n"), nil
 }
 
 func (s *service) getIndexFromLabel(label Label) (int, error) {
-	if idx, ok := s.idxMap[label]; ok {
+	if idx, ok := s.store.find(label, false); ok {
 		return idx, nil
 	}
 	return 0, fmt.Errorf("given label does not exist")
 }
 
+// getIndexFromChapterLabel returns index of first verse of chapter,
+// which isn't always verse 1 (e.g. Psalms with titles).
 func (s *service) getIndexFromChapterLabel(label Label) (int, error) {
-	// Try to hit any existing verse in label map.
-	// Verse 001 should always exist as from my research.
-	verseTry := []string{"001", "002", "003"}
-	for _, verse := range verseTry {
-		if idx, ok := s.idxMap[label+Label(verse)]; ok {
-			return idx, nil
-		}
+	if b, ok := s.store.chapter(label); ok {
+		return b.start, nil
 	}
 	return 0, fmt.Errorf("given label does not exist")
 }
@@ -173,118 +204,76 @@ func (s *service) getIndexFromChapterLabel(label Label) (int, error) {
 // chapter index (which is not always associated with verse 1).
 func (s *service) GetIndexFromLabel(label Label) (int, error) {
 	if len(label) == 6 { // Handle chapter label.
-		idx, err := s.getIndexFromChapterLabel(label)
-		if err != nil {
-			return 0, err
-		}
-		return s.GetChapterStartIndex(idx)
-
+		return s.getIndexFromChapterLabel(label)
 	} else if len(label) == 9 { // Handle regular verse label.
 		return s.getIndexFromLabel(label)
 	}
 	return 0, fmt.Errorf("invalid label length: %s", label)
 }
 
-// Rewinds to chapter start, passed index must exist.
+// GetChapterStartIndex returns index of first verse of chapter
+// with verse at index.
 func (s *service) GetChapterStartIndex(index int) (int, error) {
-	currentLabel, ok := s.labelMap[index]
+	b, ok := s.store.chapterAt(index)
 	if !ok {
 		return 0, fmt.Errorf("index does not exist: %d", index)
 	}
-	currentChapter := currentLabel.GetChapter()
-
-	chapterChanged := func(iterIndex int) bool {
-		label := s.labelMap[iterIndex]
-		if currentChapter != label.GetChapter() {
-			return true
-		}
-		return false
-	}
-
-	for i := 0; index-i >= 0; i++ {
-		if chapterChanged(index - i) {
-			return index - i + 1, nil
-		}
-	}
-	return 0, nil
+	return b.start, nil
 }
 
-// Rewinds to chapter end, passed index must exist.
+// GetChapterEndIndex returns index of last verse of chapter with
+// verse at index, passed index must exist.
 func (s *service) GetChapterEndIndex(index int) int {
-	currentLabel := s.labelMap[index]
-	currentChapter := currentLabel.GetChapter()
-
-	chapterChanged := func(iterIndex int) bool {
-		label := s.labelMap[iterIndex]
-		if currentChapter != label.GetChapter() {
-			return true
-		}
-		return false
-	}
-
-	for i := 0; index+i <= s.maxIndex; i++ {
-		if chapterChanged(index + i) {
-			return index + i - 1
-		}
-	}
-	return 0
+	b, _ := s.store.chapterAt(index)
+	return b.end
 }
 
 func (s *service) GetText(idx int) (string, error) {
-	if text, ok := s.textMap[idx]; ok {
+	if text, ok := s.store.textAt(idx); ok {
 		return text, nil
 	}
 	return "", fmt.Errorf("index does not exist")
 }
 
-func (s *service) GetVerseText(verse *Verse) ([]string, error) {
+// GetVerseText returns header and verses with inline numbers in
+// translation, verse is expected in indexes of default translation.
+func (s *service) GetVerseText(translation string, verse *Verse) ([]string, error) {
+	t, err := s.translation(translation)
+	if err != nil {
+		return nil, err
+	}
 	header, err := s.VerseHeader(verse)
 	if err != nil {
 		return nil, err
 	}
+	start, end, err := s.locate(verse, t)
+	if err != nil {
+		return nil, err
+	}
 	if verse.IsSingle() {
-		vt, err := s.GetText(verse.Start())
-		if err != nil {
-			return nil, err
-		}
-		l, err := s.GetLabel(verse.Start())
-		if err != nil {
-			return nil, err
-		}
-		return []string{header + "\n", s.getVerseFromLabel(l), vt}, nil
+		return []string{header + "\n", s.getVerseFromLabel(t.store.labels[start]), t.store.texts[start]}, nil
 	}
 
 	var texts []string
-	for i := verse.Start(); i <= verse.End(); i++ {
-		vt, err := s.GetText(i)
-		if err != nil {
-			return nil, err
-		}
-		ilvn, err := s.inlineVerseNumber(i)
-		if err != nil {
-			return nil, err
-		}
-		texts = append(texts, []string{ilvn, vt}...)
+	for i := start; i <= end; i++ {
+		texts = append(texts, []string{s.inlineVerseNumber(t.store.labels[i]), t.store.texts[i]}...)
 	}
 	return append([]string{header + "\n"}, texts...), nil
 }
 
-func (s *service) inlineVerseNumber(index int) (string, error) {
-	l, err := s.GetLabel(index)
-	if err != nil {
-		return "", err
+func (s *service) inlineVerseNumber(l Label) string {
+	// Chapter starts with verse one or its first part.
+	if v := s.getVerseFromLabel(l); v == "1" || v == "1a" {
+		return strings.Join([]string{s.getChapterFromLabel(l), s.getVerseFromLabel(l)}, ",")
 	}
-	if s.getVerseFromLabel(l) == "1" {
-		return strings.Join([]string{s.getChapterFromLabel(l), s.getVerseFromLabel(l)}, ","), nil
-	}
-	return s.getVerseFromLabel(l), nil
+	return s.getVerseFromLabel(l)
 }
 
 // GetIndexHeader returns human readable verse header from index.
 // Example: 1 Kor 2,13
 func (s *service) GetIndexHeader(idx int) ([]string, error) {
 	var header []string
-	label, ok := s.labelMap[idx]
+	label, ok := s.store.labelAt(idx)
 	if !ok {
 		return nil, fmt.Errorf("can't find verse index")
 	}
@@ -318,78 +307,9 @@ func (s *service) getBookFromLabel(l Label) (string, error) {
 	return books[0], nil
 }
 
+// VerseHeader returns reference of verse in default style.
 func (s *service) VerseHeader(v *Verse) (string, error) {
-	var header []string
-	if v.IsSingle() {
-		label := s.labelMap[v.Start()]
-		bookName, err := s.getBookFromLabel(label)
-		if err != nil {
-			return "", err
-		}
-		header = append(header, bookName, " ", s.getChapterFromLabel(label), ",", s.getVerseFromLabel(label))
-		return strings.Join(header, ""), nil
-	}
-
-	startLabel := s.labelMap[v.Start()]
-	endLabel := s.labelMap[v.End()]
-
-	switch {
-	case startLabel.GetChapter() == endLabel.GetChapter() && startLabel.GetBook() == endLabel.GetBook():
-		// Same chapter same book...
-		bookName, err := s.getBookFromLabel(startLabel)
-		if err != nil {
-			return "", err
-		}
-		header = append(header,
-			bookName,
-			" ",
-			s.getChapterFromLabel(startLabel),
-			",",
-			s.getVerseFromLabel(startLabel),
-			"-",
-			s.getVerseFromLabel(endLabel))
-		return strings.Join(header, ""), nil
-	case startLabel.GetBook() == endLabel.GetBook():
-		// Same book...
-		bookName, err := s.getBookFromLabel(startLabel)
-		if err != nil {
-			return "", err
-		}
-		header = append(header,
-			bookName,
-			" ",
-			s.getChapterFromLabel(startLabel),
-			",",
-			s.getVerseFromLabel(startLabel),
-			"-",
-			s.getChapterFromLabel(endLabel),
-			",",
-			s.getVerseFromLabel(endLabel))
-		return strings.Join(header, ""), nil
-	default:
-		// Maybe cross book...
-		startBookName, err := s.getBookFromLabel(startLabel)
-		if err != nil {
-			return "", err
-		}
-		endBookName, err := s.getBookFromLabel(startLabel)
-		if err != nil {
-			return "", err
-		}
-		header = append(header,
-			startBookName,
-			" ",
-			s.getChapterFromLabel(startLabel),
-			",",
-			s.getVerseFromLabel(startLabel),
-			" - ",
-			endBookName,
-			" ",
-			s.getChapterFromLabel(endLabel),
-			",",
-			s.getVerseFromLabel(endLabel))
-		return strings.Join(header, ""), nil
-	}
+	return s.Format([]*Verse{v}, Style{})
 }
 
 func (s *service) GetDayReferences(day int) ([]string, error) {
@@ -400,7 +320,15 @@ func (s *service) GetDayReferences(day int) ([]string, error) {
 	return refs, nil
 }
 
+// LoadPlan prepares texts of plan days in every translation.
 func (s *service) LoadPlan() error {
+	for _, t := range s.texts {
+		t.plan = s.loadPlan(t.code)
+	}
+	return nil
+}
+
+func (s *service) loadPlan(translation string) map[int][]string {
 	plan := make(map[int][]string)
 	for day, refs := range s.planRef {
 		var planText []string
@@ -409,38 +337,46 @@ func (s *service) LoadPlan() error {
 			if ref == "" {
 				continue
 			}
-			s.log.Log("msg", "processing", "ref", ref)
+			s.log.Log("msg", "processing", "ref", ref, "translation", translation)
 			p := NewParser(strings.NewReader(ref), s)
-			verse, err := p.Parse()
+			verses, err := p.Parse()
 			if err != nil {
 				s.log.Log("msg", "error while parsing ref %s", ref, "err", err)
 				continue
 			}
-			text, err := s.GetVerseText(verse)
-			if err != nil {
-				s.log.Log("msg", "error while getting text", "err", err, "ref", ref)
-				continue
+			for _, verse := range verses {
+				text, err := s.GetVerseText(translation, verse)
+				if err != nil {
+					s.log.Log("msg", "error while getting text", "err", err, "ref", ref, "translation", translation)
+					continue
+				}
+				planText = append(planText, strings.Join(text, " "))
 			}
-			planText = append(planText, strings.Join(text, " "))
 		}
 		plan[day] = planText
 	}
-	s.plan = plan
-	return nil
+	return plan
 }
 
-func New(booksPath, textPath, planPath string, log log.Logger) (Service, error) {
+// New loads books, texts and plan. First text is default translation,
+// references are resolved with its versification. Without texts
+// default translation is loaded from default path.
+func New(booksPath string, texts []TextSource, planPath string, log log.Logger) (Service, error) {
 	// Get books...
 	bookName, bookValue, err := LoadBookIndex(booksPath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Load text ...
-	text, err := LoadText(textPath)
+	if len(texts) == 0 {
+		texts = []TextSource{{Code: DefaultTranslation}}
+	}
+	// Load texts ...
+	translations, err := loadTexts(texts)
 	if err != nil {
 		return nil, err
 	}
+	primary := translations[texts[0].Code]
 
 	// Load plan references ...
 	planRef, err := LoadPlanReferences(planPath)
@@ -449,14 +385,14 @@ func New(booksPath, textPath, planPath string, log log.Logger) (Service, error)
 	}
 
 	s := &service{
-		planRef:   planRef,
-		bookName:  bookName,
-		bookValue: bookValue,
-		idxMap:    text.IndexMap,
-		labelMap:  text.LabelMap,
-		textMap:   text.TextMap,
-		maxIndex:  text.MaxIndex,
-		log:       log,
+		planRef:            planRef,
+		bookName:           bookName,
+		bookValue:          bookValue,
+		books:              newBookIndex(bookName),
+		store:              primary.store,
+		texts:              translations,
+		defaultTranslation: primary.code,
+		log:                log,
 	}
 	// Generate plan enteries...
 	err = s.LoadPlan()
@@ -7,19 +7,20 @@ import (
 func Test_service_getIndexFromChapterLabel(t *testing.T) {
 	tests := []struct {
 		name    string
-		idxMap  map[Label]int
+		labels  []Label
 		label   Label
 		want    int
 		wantErr bool
 	}{
 		{
 			"basic",
-			map[Label]int{
-				Label("001001001"): 1,
-				Label("002001001"): 2,
-				Label("003001001"): 3,
-				Label("004001001"): 4,
-				Label("005001001"): 5,
+			[]Label{
+				Label("000001001"),
+				Label("001001001"),
+				Label("002001001"),
+				Label("003001001"),
+				Label("004001001"),
+				Label("005001001"),
 			},
 			Label("001001"),
 			1,
@@ -27,12 +28,13 @@ func Test_service_getIndexFromChapterLabel(t *testing.T) {
 		},
 		{
 			"basic",
-			map[Label]int{
-				Label("001001001"): 1,
-				Label("002001001"): 2,
-				Label("003001001"): 3,
-				Label("004001001"): 4,
-				Label("005001001"): 5,
+			[]Label{
+				Label("000001001"),
+				Label("001001001"),
+				Label("002001001"),
+				Label("003001001"),
+				Label("004001001"),
+				Label("005001001"),
 			},
 			Label("002001"),
 			2,
@@ -40,12 +42,13 @@ func Test_service_getIndexFromChapterLabel(t *testing.T) {
 		},
 		{
 			"basic",
-			map[Label]int{
-				Label("001001001"): 1,
-				Label("002001001"): 2,
-				Label("003001001"): 3,
-				Label("004001001"): 4,
-				Label("005001001"): 5,
+			[]Label{
+				Label("000001001"),
+				Label("001001001"),
+				Label("002001001"),
+				Label("003001001"),
+				Label("004001001"),
+				Label("005001001"),
 			},
 			Label("003001"),
 			3,
@@ -53,12 +56,13 @@ func Test_service_getIndexFromChapterLabel(t *testing.T) {
 		},
 		{
 			"basic",
-			map[Label]int{
-				Label("001001001"): 1,
-				Label("002001001"): 2,
-				Label("003001001"): 3,
-				Label("004001001"): 4,
-				Label("005001001"): 5,
+			[]Label{
+				Label("000001001"),
+				Label("001001001"),
+				Label("002001001"),
+				Label("003001001"),
+				Label("004001001"),
+				Label("005001001"),
 			},
 			Label("004001"),
 			4,
@@ -66,12 +70,13 @@ func Test_service_getIndexFromChapterLabel(t *testing.T) {
 		},
 		{
 			"basic",
-			map[Label]int{
-				Label("001001001"): 1,
-				Label("002001001"): 2,
-				Label("003001001"): 3,
-				Label("004001001"): 4,
-				Label("005001001"): 5,
+			[]Label{
+				Label("000001001"),
+				Label("001001001"),
+				Label("002001001"),
+				Label("003001001"),
+				Label("004001001"),
+				Label("005001001"),
 			},
 			Label("005001"),
 			5,
@@ -81,7 +86,7 @@ func Test_service_getIndexFromChapterLabel(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &service{
-				idxMap: tt.idxMap,
+				store: newStore(tt.labels, make([]string, len(tt.labels))),
 			}
 			got, err := s.getIndexFromChapterLabel(tt.label)
 			if (err != nil) != tt.wantErr {
@@ -98,19 +103,20 @@ func Test_service_getIndexFromChapterLabel(t *testing.T) {
 func Test_service_GetChapterStartIndex(t *testing.T) {
 
 	tests := []struct {
-		name     string
-		labelMap map[int]Label
-		index    int
-		want     int
-		wantErr  bool
+		name    string
+		labels  []Label
+		index   int
+		want    int
+		wantErr bool
 	}{
 		{
 			"basic",
-			map[int]Label{
-				1: Label("001001001"),
-				2: Label("001001002"),
-				3: Label("001002001"),
-				4: Label("001002002"),
+			[]Label{
+				Label("000001001"),
+				Label("001001001"),
+				Label("001001002"),
+				Label("001002001"),
+				Label("001002002"),
 			},
 			4,
 			3,
@@ -118,12 +124,12 @@ func Test_service_GetChapterStartIndex(t *testing.T) {
 		},
 		{
 			"basic1",
-			map[int]Label{
-				0: Label("001000000"),
-				1: Label("001001001"),
-				2: Label("001001002"),
-				3: Label("001001003"),
-				4: Label("001001004"),
+			[]Label{
+				Label("001000000"),
+				Label("001001001"),
+				Label("001001002"),
+				Label("001001003"),
+				Label("001001004"),
 			},
 			4,
 			1,
@@ -131,7 +137,7 @@ func Test_service_GetChapterStartIndex(t *testing.T) {
 		},
 		{
 			"error",
-			map[int]Label{},
+			nil,
 			4,
 			0,
 			true,
@@ -140,7 +146,7 @@ func Test_service_GetChapterStartIndex(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &service{
-				labelMap: tt.labelMap,
+				store: newStore(tt.labels, make([]string, len(tt.labels))),
 			}
 			got, err := s.GetChapterStartIndex(tt.index)
 			if (err != nil) != tt.wantErr {
@@ -153,3 +159,23 @@ func Test_service_GetChapterStartIndex(t *testing.T) {
 		})
 	}
 }
+
+func Test_service_GetChapterEndIndex(t *testing.T) {
+	s := newCanonService(t)
+
+	tests := []struct {
+		name  string
+		index int
+		want  int
+	}{
+		{"middle of chapter", 0, 2},
+		{"single verse chapter", 9, 9},
+		{"chapter followed by first chapter of next book", 12, 13},
+		{"last chapter", 14, 15},
+	}
+	for _, tt := range tests {
+		if got := s.GetChapterEndIndex(tt.index); got != tt.want {
+			t.Errorf("%s: GetChapterEndIndex(%d) = %d, want %d", tt.name, tt.index, got, tt.want)
+		}
+	}
+}
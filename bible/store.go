@@ -0,0 +1,148 @@
+package bible
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// bounds are first and last sequential index of chapter or verse.
+type bounds struct {
+	start, end int
+}
+
+// store holds verses of text, it isn't modified after load. Sequential
+// indexes are dense, so verses are kept in slices by index and chapter
+// bounds are computed once.
+type store struct {
+	labels []Label
+	texts  []string
+	// Sequential index by label.
+	index map[Label]int
+	// Chapter bounds by book and chapter token.
+	chapters map[string]map[string]bounds
+	// Verses split into parts, e.g. "01a" and "01b", by label
+	// of whole verse.
+	parts map[Label]bounds
+}
+
+// loadStore reads text file with line per verse, e.g.
+// "001001001 Na początku Bóg stworzył niebo i ziemię.".
+func loadStore(path string) (*store, error) {
+	if path == "" {
+		path = "../data/bt.txt"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		labels []Label
+		texts  []string
+	)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		sp := strings.SplitN(sc.Text(), " ", 2)
+		if len(sp) != 2 {
+			return nil, fmt.Errorf("line %d: expected label and text", len(labels)+1)
+		}
+		labels = append(labels, Label(sp[0]))
+		texts = append(texts, sp[1])
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return newStore(labels, texts), nil
+}
+
+// newStore indexes verses given in order.
+func newStore(labels []Label, texts []string) *store {
+	st := &store{
+		labels:   labels,
+		texts:    texts,
+		index:    make(map[Label]int, len(labels)),
+		chapters: make(map[string]map[string]bounds),
+		parts:    make(map[Label]bounds),
+	}
+	for idx, label := range labels {
+		st.index[label] = idx
+
+		book, chapter := label.GetBook(), label.GetChapter()
+		if st.chapters[book] == nil {
+			st.chapters[book] = make(map[string]bounds)
+		}
+		b, ok := st.chapters[book][chapter]
+		if !ok {
+			b.start = idx
+		}
+		b.end = idx
+		st.chapters[book][chapter] = b
+
+		if label.Suffix() != "" {
+			p, ok := st.parts[label.Whole()]
+			if !ok {
+				p.start = idx
+			}
+			p.end = idx
+			st.parts[label.Whole()] = p
+		}
+	}
+	return st
+}
+
+func (st *store) maxIndex() int {
+	return len(st.labels) - 1
+}
+
+func (st *store) labelAt(idx int) (Label, bool) {
+	if idx < 0 || idx >= len(st.labels) {
+		return "", false
+	}
+	return st.labels[idx], true
+}
+
+func (st *store) textAt(idx int) (string, bool) {
+	if idx < 0 || idx >= len(st.texts) {
+		return "", false
+	}
+	return st.texts[idx], true
+}
+
+// find returns index of label. Partial verse missing in text resolves
+// to whole one, whole verse split into parts to its first part or
+// last one if last is set.
+func (st *store) find(label Label, last bool) (int, bool) {
+	if idx, ok := st.index[label]; ok {
+		return idx, true
+	}
+	if label.Suffix() != "" {
+		idx, ok := st.index[label.Whole()]
+		return idx, ok
+	}
+	if p, ok := st.parts[label]; ok {
+		if last {
+			return p.end, true
+		}
+		return p.start, true
+	}
+	return 0, false
+}
+
+// chapter returns bounds of chapter of verse or chapter label.
+func (st *store) chapter(label Label) (bounds, bool) {
+	b, ok := st.chapters[label.GetBook()][label.GetChapter()]
+	return b, ok
+}
+
+// chapterAt returns bounds of chapter of verse at index.
+func (st *store) chapterAt(idx int) (bounds, bool) {
+	label, ok := st.labelAt(idx)
+	if !ok {
+		return bounds{}, false
+	}
+	return st.chapter(label)
+}
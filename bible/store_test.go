@@ -0,0 +1,236 @@
+package bible
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_loadStore(t *testing.T) {
+	st, err := loadStore("testdata/canon.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.maxIndex() != 23 {
+		t.Errorf("maxIndex() = %d, want 23", st.maxIndex())
+	}
+	if l, ok := st.labelAt(6); !ok || l != "00200102a" {
+		t.Errorf("labelAt(6) = %q, %v", l, ok)
+	}
+	if text, ok := st.textAt(15); !ok || text != "Powtórzonego Prawa 1,2." {
+		t.Errorf("textAt(15) = %q, %v", text, ok)
+	}
+	if _, ok := st.labelAt(24); ok {
+		t.Error("labelAt(24) found verse past the end")
+	}
+
+	chapters := []struct {
+		label Label
+		want  bounds
+	}{
+		{"001001", bounds{0, 2}},
+		{"001002001", bounds{3, 4}},
+		{"003001", bounds{9, 9}},
+		{"004001", bounds{12, 13}},
+		{"005001", bounds{14, 15}},
+		{"046016", bounds{20, 21}},
+	}
+	for _, tt := range chapters {
+		if got, ok := st.chapter(tt.label); !ok || got != tt.want {
+			t.Errorf("chapter(%q) = %v, %v, want %v", tt.label, got, ok, tt.want)
+		}
+	}
+	if _, ok := st.chapter("001003"); ok {
+		t.Error("chapter(001003) found missing chapter")
+	}
+
+	finds := []struct {
+		label Label
+		last  bool
+		want  int
+		found bool
+	}{
+		{"002001001", false, 5, true},
+		{"002001002", false, 6, true},
+		{"002001002", true, 7, true},
+		{"00100101a", false, 0, true},
+		{"00200102c", false, 0, false},
+	}
+	for _, tt := range finds {
+		if got, ok := st.find(tt.label, tt.last); got != tt.want || ok != tt.found {
+			t.Errorf("find(%q, %v) = %d, %v, want %d, %v", tt.label, tt.last, got, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func Test_loadStore_malformed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "broken.txt")
+	if err := ioutil.WriteFile(path, []byte("001001001 Tekst.\n001001002\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadStore(path); err == nil || err.Error() != "line 2: expected label and text" {
+		t.Errorf("loadStore() error = %v", err)
+	}
+}
+
+// mapText is former map based text, kept to compare store with it.
+type mapText struct {
+	idxMap   map[Label]int
+	labelMap map[int]Label
+	textMap  map[int]string
+	maxIndex int
+}
+
+func loadMapText(path string) (*mapText, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mt := &mapText{
+		idxMap:   make(map[Label]int),
+		labelMap: make(map[int]Label),
+		textMap:  make(map[int]string),
+	}
+	sc := bufio.NewScanner(f)
+	var idx int
+	for sc.Scan() {
+		sp := strings.SplitN(sc.Text(), " ", 2)
+		mt.idxMap[Label(sp[0])] = idx
+		mt.labelMap[idx] = Label(sp[0])
+		mt.textMap[idx] = sp[1]
+		idx++
+	}
+	mt.maxIndex = idx - 1
+	return mt, nil
+}
+
+// chapter walks verse by verse to bounds of chapter at index.
+func (mt *mapText) chapter(index int) bounds {
+	label := mt.labelMap[index]
+	b := bounds{index, index}
+	for b.start > 0 && sameChapter(label, mt.labelMap[b.start-1]) {
+		b.start--
+	}
+	for b.end < mt.maxIndex && sameChapter(label, mt.labelMap[b.end+1]) {
+		b.end++
+	}
+	return b
+}
+
+// Size of generated text, close to the whole Bible.
+const (
+	benchBooks    = 73
+	benchChapters = 20
+	benchVerses   = 25
+)
+
+// writeBenchText writes generated text and returns its path.
+func writeBenchText(b *testing.B) (string, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		b.Fatal(err)
+	}
+	path := filepath.Join(dir, "bench.txt")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	for book := 1; book <= benchBooks; book++ {
+		for chapter := 1; chapter <= benchChapters; chapter++ {
+			for verse := 1; verse <= benchVerses; verse++ {
+				fmt.Fprintf(w, "%03d%03d%03d Na początku Bóg stworzył niebo i ziemię, wiersz %d.\n", book, chapter, verse, verse)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		b.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func BenchmarkLoad(b *testing.B) {
+	path, cleanup := writeBenchText(b)
+	defer cleanup()
+
+	b.Run("store", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := loadStore(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("maps", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := loadMapText(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLookup(b *testing.B) {
+	path, cleanup := writeBenchText(b)
+	defer cleanup()
+
+	st, err := loadStore(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	mt, err := loadMapText(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if st.labels[st.maxIndex()] != mt.labelMap[mt.maxIndex] {
+		b.Fatal("store and maps differ")
+	}
+	n := st.maxIndex() + 1
+
+	b.Run("label/store", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			st.labelAt(i % n)
+		}
+	})
+	b.Run("label/maps", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = mt.labelMap[i%n]
+		}
+	})
+	b.Run("index/store", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			st.find(st.labels[i%n], false)
+		}
+	})
+	b.Run("index/maps", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = mt.idxMap[st.labels[i%n]]
+		}
+	})
+	b.Run("chapter/store", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			st.chapterAt(i % n)
+		}
+	})
+	b.Run("chapter/maps", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mt.chapter(i % n)
+		}
+	})
+}
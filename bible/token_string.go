@@ -4,9 +4,9 @@ package bible
 
 import "strconv"
 
-const _Token_name = "ILLEGALBOOKNEXT_NUMCOMMADASHEOFWS"
+const _Token_name = "ILLEGALBOOKNEXT_NUMCOMMADASHEOFWSDOTSEMICOLON"
 
-var _Token_index = [...]uint8{0, 7, 11, 19, 24, 28, 31, 33}
+var _Token_index = [...]uint8{0, 7, 11, 19, 24, 28, 31, 33, 36, 45}
 
 func (i Token) String() string {
 	if i < 0 || i >= Token(len(_Token_index)-1) {
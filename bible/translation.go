@@ -0,0 +1,135 @@
+package bible
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultTranslation is code of Biblia Tysiąclecia, used when no
+// other text is configured.
+const DefaultTranslation = "bt"
+
+var (
+	ErrUnknownTranslation = errors.New("translation does not exist")
+	// ErrVerseNotInTranslation is returned when reference exists in
+	// default translation, but versification of requested one lacks it.
+	ErrVerseNotInTranslation = errors.New("verse does not exist in translation")
+)
+
+// TextSource is text file of single translation.
+type TextSource struct {
+	// Short code of translation, e.g. "bt".
+	Code string
+	Path string
+	// Mapping table of numbering scheme, empty when translation uses
+	// scheme of default one. Scheme is named after file.
+	Versification string
+}
+
+// text holds verses of single translation. Sequential indexes are
+// specific to translation, labels are shared by all of them.
+type text struct {
+	code  string
+	store *store
+	// Numbering scheme, nil for scheme of default translation.
+	versification *Versification
+	// Full text search index.
+	index *searchIndex
+	// Plan mapping from plan day into set of verses.
+	plan map[int][]string
+}
+
+func loadTexts(sources []TextSource) (map[string]*text, error) {
+	texts := make(map[string]*text, len(sources))
+	for _, src := range sources {
+		if _, ok := texts[src.Code]; ok {
+			return nil, fmt.Errorf("duplicated translation %q", src.Code)
+		}
+		st, err := loadStore(src.Path)
+		if err != nil {
+			return nil, fmt.Errorf("translation %s: %s", src.Code, err)
+		}
+		t := &text{code: src.Code, store: st}
+		if src.Versification != "" {
+			name := strings.TrimSuffix(filepath.Base(src.Versification), filepath.Ext(src.Versification))
+			if t.versification, err = LoadVersification(name, src.Versification); err != nil {
+				return nil, fmt.Errorf("translation %s: %s", src.Code, err)
+			}
+		}
+		t.index = buildIndex(t)
+		texts[src.Code] = t
+	}
+	return texts, nil
+}
+
+// Translations returns codes of loaded translations.
+func (s *service) Translations() []string {
+	codes := make([]string, 0, len(s.texts))
+	for code := range s.texts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func (s *service) DefaultTranslation() string {
+	return s.defaultTranslation
+}
+
+// translation returns text of code, empty code means default translation.
+func (s *service) translation(code string) (*text, error) {
+	if code == "" {
+		code = s.defaultTranslation
+	}
+	if t, ok := s.texts[code]; ok {
+		return t, nil
+	}
+	return nil, ErrUnknownTranslation
+}
+
+// locate maps verse of default translation onto indexes of t, labels
+// are converted to numbering scheme of t. Verses missing in t are dropped
+// from both ends of range, verses which exist only in t are included
+// when they fall inside of it or range reaches end of chapter.
+func (s *service) locate(verse *Verse, t *text) (int, int, error) {
+	end := verse.End()
+	if verse.IsSingle() {
+		end = verse.Start()
+	}
+	first, last := -1, -1
+	for i := verse.Start(); i <= end; i++ {
+		if idx, ok := t.store.find(t.versification.FromReference(s.store.labels[i]), false); ok {
+			first = idx
+			break
+		}
+	}
+	if first < 0 {
+		return 0, 0, ErrVerseNotInTranslation
+	}
+	for i := end; i >= verse.Start(); i-- {
+		if idx, ok := t.store.find(t.versification.FromReference(s.store.labels[i]), true); ok {
+			last = idx
+			break
+		}
+	}
+	if s.GetChapterEndIndex(end) == end {
+		chapter := t.store.labels[last]
+		for next, ok := t.store.labelAt(last + 1); ok && sameChapter(next, chapter) && !s.hasLabel(t, next); next, ok = t.store.labelAt(last + 1) {
+			last++
+		}
+	}
+	return first, last, nil
+}
+
+func sameChapter(a, b Label) bool {
+	return a.GetBook() == b.GetBook() && a.GetChapter() == b.GetChapter()
+}
+
+// hasLabel reports whether label of t exists in default translation.
+func (s *service) hasLabel(t *text, l Label) bool {
+	_, ok := s.store.find(t.versification.ToReference(l), false)
+	return ok
+}
@@ -0,0 +1,113 @@
+package bible
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func Test_service_GetTextByReference_translations(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name        string
+		translation string
+		ref         string
+		want        string
+		wantErr     error
+	}{
+		{"default", "", "rodz 1,1",
+			"rodz 1,1\n 1 Na początku Bóg stworzył niebo i ziemię.", nil},
+		{"by code", "bw", "rodz 1,1",
+			"rodz 1,1\n 1 Na początku stworzył Bóg niebo i ziemię.", nil},
+		{"missing verse", "bw", "rodz 1,3", "", ErrVerseNotInTranslation},
+		{"missing end of range", "bw", "rodz 1,2-3",
+			"rodz 1,2-3\n 2 A ziemia była pustkowiem i chaosem; ciemność była nad otchłanią, a Duch Boży unosił się nad powierzchnią wód.", nil},
+		{"extra verse in chapter", "bw", "rodz 2",
+			"rodz 2,1-2\n 2,1 Tak zostały ukończone niebo i ziemia oraz wszystkie ich zastępy. " +
+				"2 I ukończył Bóg dnia siódmego dzieło swoje, które uczynił, i odpoczął dnia siódmego od wszelkiego dzieła swego, które uczynił. " +
+				"3 I pobłogosławił Bóg dzień siódmy, i poświęcił go, gdyż w nim odpoczął od wszelkiego dzieła swego, którego Bóg dokonał w stworzeniu.", nil},
+		{"unknown translation", "kjv", "rodz 1,1", "", ErrUnknownTranslation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetTextByReference(tt.translation, tt.ref)
+			if err != tt.wantErr {
+				t.Fatalf("GetTextByReference() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetTextByReference() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_service_GetTextByReference_parts(t *testing.T) {
+	texts := []TextSource{{Code: "pt", Path: "testdata/pt.txt"}, {Code: "bt", Path: "testdata/bt.txt"}}
+	s, err := New("../data/ksiegi.txt", texts, "testdata/plan.csv", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		translation string
+		ref         string
+		want        string
+	}{
+		{"part", "pt", "rodz 1,2b",
+			"rodz 1,2b\n 2b ciemność była nad powierzchnią bezmiaru wód, a Duch Boży unosił się nad wodami."},
+		{"verse of parts", "pt", "rodz 1,1-2",
+			"rodz 1,1-2b\n 1,1 Na początku Bóg stworzył niebo i ziemię. 2a Ziemia zaś była bezładem i pustkowiem: 2b ciemność była nad powierzchnią bezmiaru wód, a Duch Boży unosił się nad wodami."},
+		{"part in text of whole verses", "bt", "rodz 1,2a",
+			"rodz 1,2a\n 2 Ziemia zaś była bezładem i pustkowiem: ciemność była nad powierzchnią bezmiaru wód, a Duch Boży unosił się nad wodami."},
+		{"parts in text of whole verses", "bt", "rodz 1,2-3",
+			"rodz 1,2a-3\n 2 Ziemia zaś była bezładem i pustkowiem: ciemność była nad powierzchnią bezmiaru wód, a Duch Boży unosił się nad wodami. 3 Wtedy Bóg rzekł: «Niechaj się stanie światłość!» I stała się światłość."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetTextByReference(tt.translation, tt.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetTextByReference() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_service_GetDay_translations(t *testing.T) {
+	s := newTestService(t)
+
+	bt, err := s.GetDay("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bw, err := s.GetDay("bw", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bt) != 2 || len(bw) != 2 || reflect.DeepEqual(bt, bw) {
+		t.Errorf("GetDay() bt = %q, bw = %q, want two different readings", bt, bw)
+	}
+	if _, err := s.GetDay("kjv", 1); err != ErrUnknownTranslation {
+		t.Errorf("GetDay() error = %v, want %v", err, ErrUnknownTranslation)
+	}
+}
+
+func TestNew_translations(t *testing.T) {
+	s := newTestService(t)
+	if got, want := s.Translations(), []string{"bt", "bw"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Translations() = %v, want %v", got, want)
+	}
+	if got := s.DefaultTranslation(); got != "bt" {
+		t.Errorf("DefaultTranslation() = %q, want bt", got)
+	}
+
+	texts := []TextSource{{Code: "bt", Path: "testdata/bt.txt"}, {Code: "bt", Path: "testdata/bw.txt"}}
+	if _, err := New("../data/ksiegi.txt", texts, "testdata/plan.csv", log.NewNopLogger()); err == nil {
+		t.Error("New() with duplicated translation should fail")
+	}
+}
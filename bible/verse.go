@@ -1,5 +1,7 @@
 package bible
 
+import "strings"
+
 const (
 	defaultVerse = "001"
 )
@@ -45,7 +47,8 @@ func (v *Verse) End() int {
 // Label represents string label of verse.
 // Example: "001002003" - means book one, chapter two, verse three.
 // Each token have reserver 3 digits.
-// Verse token could contain alphabet literals like "01a".
+// Verse token could contain alphabet literals like "01a", which
+// is first part of verse one.
 type Label string
 
 // GetChapter - returns chapter token with default "001".
@@ -69,3 +72,21 @@ func (l Label) GetVerse() string {
 	}
 	return defaultVerse
 }
+
+// Suffix returns letter of partial verse, e.g. "a" of "00100101a",
+// empty for whole verse.
+func (l Label) Suffix() string {
+	verse := l.GetVerse()
+	return strings.TrimLeft(verse, "0123456789")
+}
+
+// Whole returns label of verse which l is part of, e.g. "001001001"
+// for "00100101a".
+func (l Label) Whole() Label {
+	suffix := l.Suffix()
+	if suffix == "" {
+		return l
+	}
+	verse := strings.TrimSuffix(l.GetVerse(), suffix)
+	return Label(l.GetBook() + l.GetChapter() + strings.Repeat("0", 3-len(verse)) + verse)
+}
@@ -0,0 +1,141 @@
+package bible
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Versification maps labels of numbering scheme onto scheme of default
+// translation (reference scheme) and back. Nil versification is the
+// reference scheme itself.
+type Versification struct {
+	Name string
+	// Verse and chapter mappings into reference scheme.
+	verses   map[Label]Label
+	chapters map[Label]Label
+	// Inverse mappings, from reference scheme.
+	refVerses   map[Label]Label
+	refChapters map[Label]Label
+}
+
+// LoadVersification reads mapping table of scheme. Every line maps
+// label of scheme onto label of reference scheme:
+//
+//	019010 019011                  - whole chapter, verse numbers are kept
+//	019009022-019009039 019010001  - consecutive verses from target
+//
+// Verse mappings take precedence over chapter ones, labels which
+// aren't mapped are the same in both schemes.
+func LoadVersification(name, path string) (*Versification, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	v := &Versification{
+		Name:        name,
+		verses:      make(map[Label]Label),
+		chapters:    make(map[Label]Label),
+		refVerses:   make(map[Label]Label),
+		refChapters: make(map[Label]Label),
+	}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := v.parseLine(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+	}
+	return v, sc.Err()
+}
+
+func (v *Versification) parseLine(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return fmt.Errorf("expected source and target label: %q", line)
+	}
+	from, to := fields[0], Label(fields[1])
+
+	if len(from) == 6 && len(to) == 6 {
+		return add(v.chapters, v.refChapters, Label(from), to)
+	}
+	if len(to) != 9 {
+		return fmt.Errorf("invalid target label %q", to)
+	}
+	start, end := from, from
+	if i := strings.Index(from, "-"); i >= 0 {
+		start, end = from[:i], from[i+1:]
+	}
+	if len(start) != 9 || len(end) != 9 || start[:6] != end[:6] {
+		return fmt.Errorf("invalid source range %q", from)
+	}
+	first, err1 := strconv.Atoi(start[6:])
+	last, err2 := strconv.Atoi(end[6:])
+	target, err3 := strconv.Atoi(to.GetVerse())
+	if err1 != nil || err2 != nil || err3 != nil || last < first {
+		return fmt.Errorf("invalid verse numbers in %q", line)
+	}
+	for i := 0; i <= last-first; i++ {
+		src := Label(fmt.Sprintf("%s%03d", start[:6], first+i))
+		dst := Label(fmt.Sprintf("%s%03d", to[:6], target+i))
+		if err := add(v.verses, v.refVerses, src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func add(forward, inverse map[Label]Label, from, to Label) error {
+	if _, ok := forward[from]; ok {
+		return fmt.Errorf("label %s is mapped twice", from)
+	}
+	if _, ok := inverse[to]; ok {
+		return fmt.Errorf("label %s is target of two mappings", to)
+	}
+	forward[from] = to
+	inverse[to] = from
+	return nil
+}
+
+// ToReference converts label of scheme into reference scheme.
+func (v *Versification) ToReference(l Label) Label {
+	if v == nil {
+		return l
+	}
+	return convert(l, v.verses, v.chapters)
+}
+
+// FromReference converts label of reference scheme into scheme.
+func (v *Versification) FromReference(l Label) Label {
+	if v == nil {
+		return l
+	}
+	return convert(l, v.refVerses, v.refChapters)
+}
+
+// ConvertLabel converts label between schemes, nil means reference scheme.
+func ConvertLabel(l Label, from, to *Versification) Label {
+	return to.FromReference(from.ToReference(l))
+}
+
+func convert(l Label, verses, chapters map[Label]Label) Label {
+	if len(l) == 9 {
+		if m, ok := verses[l]; ok {
+			return m
+		}
+	}
+	if len(l) < 6 {
+		return l
+	}
+	if m, ok := chapters[l[:6]]; ok {
+		return m + l[6:]
+	}
+	return l
+}
@@ -0,0 +1,117 @@
+package bible
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestVersification_vulgate(t *testing.T) {
+	v, err := LoadVersification("vulgate", "../data/versification/vulgate.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		vulgate   Label
+		reference Label
+	}{
+		{"not mapped", "019001001", "019001001"},
+		{"other book", "001010005", "001010005"},
+		{"first part of split psalm", "019009021", "019009021"},
+		{"second part of split psalm", "019009022", "019010001"},
+		{"shifted psalm", "019022001", "019023001"},
+		{"merged psalm", "019113009", "019115001"},
+		{"merged psalm second part", "019115010", "019116019"},
+		{"split psalm", "019146011", "019147011"},
+		{"split psalm second part", "019147001", "019147012"},
+		{"chapter label", "019050", "019051"},
+		{"joel before split", "029002027", "029002027"},
+		{"joel split", "029002028", "029003001"},
+		{"joel split end", "029002032", "029003005"},
+		{"joel shifted chapter", "029003001", "029004001"},
+		{"malachi split", "039004001", "039003019"},
+		{"malachi split end", "039004006", "039003024"},
+		{"malachi not mapped", "039003018", "039003018"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.ToReference(tt.vulgate); got != tt.reference {
+				t.Errorf("ToReference(%s) = %s, want %s", tt.vulgate, got, tt.reference)
+			}
+			if got := v.FromReference(tt.reference); got != tt.vulgate {
+				t.Errorf("FromReference(%s) = %s, want %s", tt.reference, got, tt.vulgate)
+			}
+		})
+	}
+
+	if got := ConvertLabel("019023001", nil, v); got != "019022001" {
+		t.Errorf("ConvertLabel() = %s, want 019022001", got)
+	}
+	if got := ConvertLabel("019022001", v, v); got != "019022001" {
+		t.Errorf("ConvertLabel() = %s, want 019022001", got)
+	}
+}
+
+func TestLoadVersification_errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "versification")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		table string
+	}{
+		{"single field", "019010\n"},
+		{"mixed labels", "019010 019011001\n"},
+		{"range across chapters", "019009022-019010001 019010001\n"},
+		{"reversed range", "019009039-019009022 019010001\n"},
+		{"mapped twice", "019010 019011\n019010 019012\n"},
+		{"same target", "019010001 019011001\n019010002 019011001\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "table.txt")
+			if err := ioutil.WriteFile(path, []byte(tt.table), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadVersification("test", path); err == nil {
+				t.Errorf("LoadVersification() should fail for %q", tt.table)
+			}
+		})
+	}
+}
+
+func Test_service_versification(t *testing.T) {
+	texts := []TextSource{
+		{Code: "bt", Path: "testdata/bt.txt"},
+		{Code: "sw", Path: "testdata/sw.txt", Versification: "testdata/swapped.txt"},
+	}
+	s, err := New("../data/ksiegi.txt", texts, "testdata/plan.csv", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetTextByReference("sw", "rodz 1,2-3")
+	if want := "rodz 1,2-3\n 2 Sw 1,2. 3 Sw 1,3."; err != nil || got != want {
+		t.Errorf("GetTextByReference() = %q, %v, want %q", got, err, want)
+	}
+	got, err = s.GetTextByReference("sw", "rodz 2")
+	if want := "rodz 2,1-2\n 1,1 Sw 2,1. 2 Sw 2,2."; err != nil || got != want {
+		t.Errorf("GetTextByReference() = %q, %v, want %q", got, err, want)
+	}
+
+	p, err := s.GetParallel("rodz 1,1", []string{"bt", "sw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Verses) != 1 || p.Verses[0].Label != "001001001" || p.Verses[0].Texts[1] != "Sw 1,1." {
+		t.Errorf("GetParallel() = %+v, want verse 001001001 aligned", p)
+	}
+}
@@ -5,14 +5,18 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
 
 	"github.com/gorilla/handlers"
 
 	"github.com/go-kit/kit/log"
 
 	"github.com/gorilla/mux"
+	"github.com/jozuenoon/biblia2y/bible"
 	"github.com/jozuenoon/biblia2y/messenger"
 	"github.com/jozuenoon/biblia2y/pages/privacyPolicy"
+	"github.com/jozuenoon/biblia2y/poster"
+	"github.com/jozuenoon/biblia2y/profile"
 	"github.com/stevenroose/gonfig"
 	validator "gopkg.in/go-playground/validator.v9"
 )
@@ -20,21 +24,50 @@ import (
 var config = struct {
 	ServerPort string `id:"server_port" validate:"required"`
 
-	VerifyToken     string `id:"verify_token" validate:"required"`
+	VerifyToken string `id:"verify_token" validate:"required"`
+	// App secret used to verify signatures of webhook callbacks.
+	AppSecret       string `id:"app_secret" validate:"required"`
 	PageAccessToken string `id:"page_access_token" validate:"required"`
 	TLSCert         string `id:"tls_cert" validate:"required"`
 	TLSKey          string `id:"tls_key" validate:"required"`
 	FaceBookAPI     string `id:"facebook_api" validate:"required"`
+	// Configure Get Started button, greeting and menu on startup.
+	SetupProfile bool   `id:"setup_profile"`
+	ProfileAPI   string `id:"profile_api"`
+
+	// Telegram bot is enabled when token is set, secret is required
+	// with it, otherwise anyone could post updates as any user.
+	TelegramToken  string `id:"telegram_token"`
+	TelegramAPI    string `id:"telegram_api"`
+	TelegramSecret string `id:"telegram_secret"`
 
 	DatabasePath string `id:"database_path" validate:"required"`
 	BooksPath    string `id:"books_path" validate:"required"`
 	TextPath     string `id:"text_path" validate:"required"`
 	PlanPath     string `id:"plan_path" validate:"required"`
+	// Code of text_path translation, it's the default one.
+	Translation string `id:"translation"`
+	// Additional translations, code to text path.
+	Translations map[string]interface{} `id:"translations"`
+	// Numbering schemes of additional translations, code to mapping
+	// table path. Translations without it use numbering of default one.
+	Versifications map[string]interface{} `id:"versifications"`
+
+	// Send API limits, zero means default.
+	SendRate      float64 `id:"send_rate"`
+	SendBurst     int     `id:"send_burst"`
+	SendWorkers   int     `id:"send_workers"`
+	SendQueueSize int     `id:"send_queue_size"`
+
+	// Inbound queue of webhook messages, zero means default.
+	InboxWorkers   int `id:"inbox_workers"`
+	InboxQueueSize int `id:"inbox_queue_size"`
 
 	ConfigFile string `id:"config_file"`
 }{
 	ServerPort:   ":443",
 	DatabasePath: "db",
+	Translation:  bible.DefaultTranslation,
 }
 
 func main() {
@@ -55,15 +88,42 @@ func main() {
 	if err := validator.New().Struct(config); err != nil {
 		panicf("invalid config: %s", err)
 	}
+	if config.TelegramToken != "" && config.TelegramSecret == "" {
+		panicf("invalid config: telegram_secret is required with telegram_token")
+	}
+
+	if config.SetupProfile {
+		api := profile.NewGraph(config.PageAccessToken, config.ProfileAPI)
+		if _, err := profile.Sync(api, messenger.MessengerProfile(), logger); err != nil {
+			// Bot works without menu, don't block startup.
+			logger.Log("msg", "failed to set up messenger profile", "err", err)
+		}
+	}
 
 	done := make(chan struct{})
 
+	transports := map[string]poster.Transport{
+		messenger.Facebook: poster.NewFacebook(config.PageAccessToken, config.FaceBookAPI),
+	}
+	if config.TelegramToken != "" {
+		transports[messenger.Telegram] = poster.NewTelegram(config.TelegramToken, config.TelegramAPI)
+	}
+
 	bs, err := messenger.New(config.DatabasePath,
-		config.PageAccessToken,
-		config.FaceBookAPI,
+		transports,
 		config.BooksPath,
-		config.TextPath,
+		texts(),
 		config.PlanPath,
+		poster.Options{
+			Rate:      config.SendRate,
+			Burst:     config.SendBurst,
+			Workers:   config.SendWorkers,
+			QueueSize: config.SendQueueSize,
+		},
+		messenger.InboxOptions{
+			Workers:   config.InboxWorkers,
+			QueueSize: config.InboxQueueSize,
+		},
 		logger,
 		done)
 	if err != nil {
@@ -72,7 +132,10 @@ func main() {
 
 	mux := mux.NewRouter()
 
-	mux.PathPrefix("/webhook").Handler(messenger.MakeHandler(bs, logger, config.VerifyToken))
+	mux.PathPrefix("/webhook").Handler(messenger.MakeHandler(bs, logger, config.VerifyToken, config.AppSecret))
+	if config.TelegramToken != "" {
+		mux.PathPrefix("/telegram").Handler(messenger.MakeTelegramHandler(bs, logger, config.TelegramSecret))
+	}
 	mux.HandleFunc("/privacyPolicy", privacyPolicy.Handler)
 
 	h := handlers.LoggingHandler(os.Stderr, mux)
@@ -86,6 +149,30 @@ func main() {
 	logger.Log("terminated", err)
 }
 
+// texts lists configured translations, default one goes first.
+func texts() []bible.TextSource {
+	texts := []bible.TextSource{{Code: config.Translation, Path: config.TextPath}}
+	codes := make([]string, 0, len(config.Translations))
+	for code := range config.Translations {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		path, ok := config.Translations[code].(string)
+		if !ok {
+			panicf("invalid path of translation %s: %v", code, config.Translations[code])
+		}
+		text := bible.TextSource{Code: code, Path: path}
+		if table, ok := config.Versifications[code]; ok {
+			if text.Versification, ok = table.(string); !ok {
+				panicf("invalid versification of translation %s: %v", code, table)
+			}
+		}
+		texts = append(texts, text)
+	}
+	return texts
+}
+
 func panicf(s string, i ...interface{}) {
 	panic(fmt.Sprintf(s, i...))
 }
@@ -9,7 +9,6 @@ require (
 	github.com/go-playground/universal-translator v0.16.0 // indirect
 	github.com/gorilla/handlers v1.4.0
 	github.com/gorilla/mux v1.7.0
-	github.com/jasonlvhit/gocron v0.0.0-20190121134850-6771d4b492ba
 	github.com/leodido/go-urn v1.1.0 // indirect
 	github.com/stevenroose/gonfig v0.1.4
 	github.com/syndtr/goleveldb v1.0.0
@@ -0,0 +1,71 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Catch up policies, applied when scheduled deliveries
+// were missed because service was down or stalled.
+const (
+	// Send every missed day as separate delivery.
+	catchUpSend = "send"
+	// Send all missed days in one consolidated delivery.
+	catchUpMerge = "merge"
+	// Don't send missed days, plan is shifted by down time.
+	catchUpSkip = "skip"
+
+	// Limit of days delivered at once after long down time.
+	maxCatchUpDays = 7
+	// Limit of slots inspected, protects from iterating over years.
+	maxMissedSlots = 366
+)
+
+// missedSlots returns delivery slots in (last, now], one for each
+// calendar day (in loc) after the day of last delivery. Returns nil
+// if user never received any delivery.
+func missedSlots(last, now, at time.Time, loc *time.Location) []time.Time {
+	if last.IsZero() {
+		return nil
+	}
+	lastLocal := last.In(loc)
+	var slots []time.Time
+	for d := 1; d <= maxMissedSlots; d++ {
+		slot := time.Date(lastLocal.Year(), lastLocal.Month(), lastLocal.Day()+d, at.Hour(), at.Minute(), 0, 0, loc)
+		if slot.After(now) {
+			break
+		}
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+func parseCatchUpCommand(msg string) (string, error) {
+	policy := strings.Trim(strings.TrimPrefix(msg, setCatchUpCommand), " ;[]{}'.,/\\|?")
+	switch policy {
+	case catchUpSend, catchUpMerge, catchUpSkip:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown catch up policy %q, use one of: send, skip, merge", policy)
+}
+
+// catchUpDays returns number of plan days which should be delivered now.
+func (u *User) catchUpDays(now time.Time) int {
+	slots := len(missedSlots(u.lastSlot(), now, u.ScheduleTime, u.location()))
+	// Unconfirmed day is repeated instead of moving forward.
+	if slots <= 1 || u.catchUpPolicy() == catchUpSkip || u.RequireConfirm {
+		return 1
+	}
+	if slots > maxCatchUpDays {
+		return maxCatchUpDays
+	}
+	return slots
+}
+
+func (u *User) catchUpPolicy() string {
+	if u.CatchUp == "" {
+		return catchUpSend
+	}
+	return u.CatchUp
+}
@@ -0,0 +1,124 @@
+package messenger
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_missedSlots(t *testing.T) {
+	at := time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)
+	last := time.Date(2019, 3, 1, 8, 0, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		last time.Time
+		now  time.Time
+		want int
+	}{
+		{"never delivered", time.Time{}, last.Add(72 * time.Hour), 0},
+		{"same day", last, last.Add(time.Hour), 0},
+		{"next slot not yet due", last, last.Add(23 * time.Hour), 0},
+		{"next slot due", last, last.Add(24 * time.Hour), 1},
+		{"three days down", last, last.Add(3*24*time.Hour + time.Minute), 3},
+		{"schedule moved earlier same day", time.Date(2019, 3, 1, 9, 0, 0, 0, time.UTC), time.Date(2019, 3, 1, 9, 30, 0, 0, time.UTC), 0},
+		{"very long down time", last, last.AddDate(3, 0, 0), maxMissedSlots},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := missedSlots(tt.last, tt.now, at, time.UTC)
+			if len(got) != tt.want {
+				t.Errorf("missedSlots() = %v, want %d slots", got, tt.want)
+			}
+			for _, slot := range got {
+				if slot.After(tt.now) || !slot.After(tt.last) {
+					t.Errorf("missedSlots() slot %v outside (%v, %v]", slot, tt.last, tt.now)
+				}
+			}
+		})
+	}
+}
+
+func TestUser_catchUpDays(t *testing.T) {
+	last := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	user := func(policy string) *User {
+		return &User{
+			ScheduleTime:    time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+			Zone:            "UTC",
+			LastDeliveredAt: last,
+			CatchUp:         policy,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		now    time.Time
+		want   int
+	}{
+		{"regular delivery", catchUpSend, last.Add(24 * time.Hour), 1},
+		{"default policy sends", "", last.Add(3 * 24 * time.Hour), 3},
+		{"merge", catchUpMerge, last.Add(3 * 24 * time.Hour), 3},
+		{"skip", catchUpSkip, last.Add(3 * 24 * time.Hour), 1},
+		{"limited", catchUpSend, last.Add(30 * 24 * time.Hour), maxCatchUpDays},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := user(tt.policy).catchUpDays(tt.now); got != tt.want {
+				t.Errorf("User.catchUpDays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUser_catchUpDaysAfterPause(t *testing.T) {
+	last := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		pausedUntil time.Time
+		now         time.Time
+		want        int
+	}{
+		{"first delivery after pause", last.Add(7*24*time.Hour + 2*time.Hour), last.Add(8 * 24 * time.Hour), 1},
+		{"down time after pause", last.Add(7*24*time.Hour + 2*time.Hour), last.Add(10 * 24 * time.Hour), 3},
+		{"resumed early", last.Add(2*24*time.Hour + 2*time.Hour), last.Add(3 * 24 * time.Hour), 1},
+		{"old pause", last.Add(-48 * time.Hour), last.Add(3 * 24 * time.Hour), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{
+				ScheduleTime:    time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+				Zone:            "UTC",
+				LastDeliveredAt: last,
+				PausedUntil:     tt.pausedUntil,
+			}
+			if got := u.catchUpDays(tt.now); got != tt.want {
+				t.Errorf("User.catchUpDays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseCatchUpCommand(t *testing.T) {
+	tests := []struct {
+		msg     string
+		want    string
+		wantErr bool
+	}{
+		{"set catchup send", catchUpSend, false},
+		{"set catchup merge ", catchUpMerge, false},
+		{"set catchup skip.", catchUpSkip, false},
+		{"set catchup never", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := parseCatchUpCommand(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseCatchUpCommand() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseCatchUpCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
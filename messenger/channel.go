@@ -0,0 +1,95 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jozuenoon/biblia2y/poster"
+)
+
+// Channels users could talk to bot with.
+const (
+	Facebook = "facebook"
+	Telegram = "telegram"
+)
+
+// userID returns ID of user record for channel specific sender ID.
+// Facebook users keep bare IDs, so records created before other
+// channels were added stay valid. IDs never contain "/" which is
+// reserved for other records.
+func userID(channel, senderID string) string {
+	if channel == "" || channel == Facebook {
+		return senderID
+	}
+	return channel + ":" + senderID
+}
+
+// splitUserID returns channel and channel specific sender ID of user record.
+func splitUserID(id string) (channel, senderID string) {
+	if i := strings.Index(id, ":"); i > 0 {
+		return id[:i], id[i+1:]
+	}
+	return Facebook, id
+}
+
+// channels routes messages to poster of user channel.
+type channels map[string]poster.Service
+
+var _ poster.Service = channels(nil)
+
+func (c channels) poster(id string) (poster.Service, string, error) {
+	channel, senderID := splitUserID(id)
+	p, ok := c[channel]
+	if !ok {
+		return nil, "", &poster.SendError{
+			Err:   fmt.Errorf("channel %q is not configured", channel),
+			Class: poster.Permanent,
+		}
+	}
+	return p, senderID, nil
+}
+
+func (c channels) ProcessMessages(recipient string, messages []string, kind poster.Kind) error {
+	p, senderID, err := c.poster(recipient)
+	if err != nil {
+		return err
+	}
+	return p.ProcessMessages(senderID, messages, kind)
+}
+
+func (c channels) Send(recipient string, messages []poster.Message, kind poster.Kind) error {
+	p, senderID, err := c.poster(recipient)
+	if err != nil {
+		return err
+	}
+	return p.Send(senderID, messages, kind)
+}
+
+func (c channels) Profile(recipient string) (*poster.Profile, error) {
+	p, senderID, err := c.poster(recipient)
+	if err != nil {
+		return nil, err
+	}
+	return p.Profile(senderID)
+}
+
+// Close stops sending on all channels.
+func (c channels) Close() {
+	for _, p := range c {
+		p.Close()
+	}
+}
+
+// Stats sums metrics of all channels.
+func (c channels) Stats() poster.Stats {
+	var st poster.Stats
+	for _, p := range c {
+		s := p.Stats()
+		st.Workers += s.Workers
+		st.Queued += s.Queued
+		st.InFlight += s.InFlight
+		st.Sent += s.Sent
+		st.Failed += s.Failed
+	}
+	return st
+}
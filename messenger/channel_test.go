@@ -0,0 +1,67 @@
+package messenger
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jozuenoon/biblia2y/poster"
+)
+
+func Test_userID(t *testing.T) {
+	tests := []struct {
+		name     string
+		channel  string
+		senderID string
+		want     string
+	}{
+		{"legacy", "", "1234", "1234"},
+		{"facebook", Facebook, "1234", "1234"},
+		{"telegram", Telegram, "1234", "telegram:1234"},
+		{"telegram group", Telegram, "-1001234", "telegram:-1001234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := userID(tt.channel, tt.senderID)
+			if got != tt.want {
+				t.Errorf("userID() = %v, want %v", got, tt.want)
+			}
+			if !isUserKey([]byte(got)) {
+				t.Errorf("userID() = %v is not user key", got)
+			}
+			wantChannel := tt.channel
+			if wantChannel == "" {
+				wantChannel = Facebook
+			}
+			channel, senderID := splitUserID(got)
+			if channel != wantChannel || senderID != tt.senderID {
+				t.Errorf("splitUserID() = %v, %v, want %v, %v", channel, senderID, wantChannel, tt.senderID)
+			}
+		})
+	}
+}
+
+func Test_channels(t *testing.T) {
+	fb, tg := &fakePoster{}, &fakePoster{}
+	c := channels{Facebook: fb, Telegram: tg}
+
+	if err := c.ProcessMessages("1234", []string{"fb"}, poster.Reply); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ProcessMessages("telegram:1234", []string{"tg"}, poster.Reply); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fb.to, []string{"1234"}) || !reflect.DeepEqual(fb.sent, []string{"fb"}) {
+		t.Errorf("facebook got %v %v", fb.to, fb.sent)
+	}
+	if !reflect.DeepEqual(tg.to, []string{"1234"}) || !reflect.DeepEqual(tg.sent, []string{"tg"}) {
+		t.Errorf("telegram got %v %v", tg.to, tg.sent)
+	}
+	if st := c.Stats(); st.Sent != 2 {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	err := c.ProcessMessages("whatsapp:1234", []string{"x"}, poster.Reply)
+	if err == nil || poster.IsRetryable(err) {
+		t.Errorf("unconfigured channel error = %v", err)
+	}
+}
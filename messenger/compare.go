@@ -0,0 +1,65 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jozuenoon/biblia2y/bible"
+)
+
+// Shown in place of verse which translation lacks.
+const missingVerse = "[missing]"
+
+// parseCompareCommand splits "compare dz 1,1-3 bt bw" into reference
+// and translation codes, codes are taken from the end of message.
+func parseCompareCommand(msg string, available []string) (string, []string, error) {
+	known := make(map[string]bool, len(available))
+	for _, code := range available {
+		known[code] = true
+	}
+	fields := strings.Fields(strings.TrimPrefix(msg, compareCommand))
+	n := len(fields)
+	for n > 0 && known[fields[n-1]] {
+		n--
+	}
+	ref, codes := strings.Join(fields[:n], " "), fields[n:]
+	if ref == "" || len(codes) < 2 {
+		return "", nil, fmt.Errorf("Use *compare dz 1,1-3 bt bw* with two of translations: %s", strings.Join(available, ", "))
+	}
+	return ref, codes, nil
+}
+
+// Compare shows reference in several translations verse by verse.
+func (s *service) Compare(msg string) string {
+	ref, codes, err := parseCompareCommand(msg, s.bsvc.Translations())
+	if err != nil {
+		return err.Error()
+	}
+	p, err := s.bsvc.GetParallel(ref, codes)
+	switch {
+	case err == bible.ErrVerseNotInTranslation:
+		return fmt.Sprintf("%s doesn't exist in any of translations: %s", ref, strings.Join(codes, ", "))
+	case err != nil:
+		s.log.Log("msg", "compare error", "ref", ref, "err", err)
+		return "Sorry! Can't find " + ref + "."
+	}
+	return renderParallel(p)
+}
+
+// renderParallel interleaves translations: verse number followed
+// by line of every translation.
+func renderParallel(p *bible.Parallel) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* (%s)\n", p.Header, strings.Join(p.Translations, " | "))
+	for _, v := range p.Verses {
+		fmt.Fprintf(&b, "\n%s\n", v.Number)
+		for i, code := range p.Translations {
+			text := v.Texts[i]
+			if text == "" {
+				text = missingVerse
+			}
+			fmt.Fprintf(&b, "%s: %s\n", code, text)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,51 @@
+package messenger
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jozuenoon/biblia2y/bible"
+)
+
+func Test_parseCompareCommand(t *testing.T) {
+	available := []string{"bt", "bw"}
+	tests := []struct {
+		msg     string
+		ref     string
+		codes   []string
+		wantErr bool
+	}{
+		{"compare dz 1,1-3 bt bw", "dz 1,1-3", []string{"bt", "bw"}, false},
+		{"compare 1 kor 13 bw bt", "1 kor 13", []string{"bw", "bt"}, false},
+		{"compare dz 1,1-3 bw", "", nil, true},
+		{"compare dz 1,1-3 bt kjv", "", nil, true},
+		{"compare bt bw", "", nil, true},
+	}
+	for _, tt := range tests {
+		ref, codes, err := parseCompareCommand(tt.msg, available)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCompareCommand(%q) error = %v, wantErr %v", tt.msg, err, tt.wantErr)
+			continue
+		}
+		if ref != tt.ref || !reflect.DeepEqual(codes, tt.codes) {
+			t.Errorf("parseCompareCommand(%q) = %q, %q, want %q, %q", tt.msg, ref, codes, tt.ref, tt.codes)
+		}
+	}
+}
+
+func Test_renderParallel(t *testing.T) {
+	p := &bible.Parallel{
+		Header:       "rodz 2,1-2",
+		Translations: []string{"bt", "bw"},
+		Verses: []bible.ParallelVerse{
+			{Label: "001002001", Number: "2,1", Texts: []string{"bt 1", "bw 1"}},
+			{Label: "001002002", Number: "2", Texts: []string{"", "bw 2"}},
+		},
+	}
+	want := "*rodz 2,1-2* (bt | bw)\n" +
+		"\n2,1\nbt: bt 1\nbw: bw 1\n" +
+		"\n2\nbt: [missing]\nbw: bw 2"
+	if got := renderParallel(p); got != want {
+		t.Errorf("renderParallel() = %q, want %q", got, want)
+	}
+}
@@ -2,7 +2,9 @@ package messenger
 
 import (
 	"context"
-	"reflect"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/kit/endpoint"
 	m "github.com/jozuenoon/biblia2y/models"
@@ -14,12 +16,14 @@ func makeMessagesEndPoint(s Service) endpoint.Endpoint {
 		if ok && callback.Object == "page" {
 			for _, entry := range callback.Entry {
 				for _, event := range entry.Messaging {
-					if !reflect.DeepEqual(event.Message, m.Message{}) && event.Message.Text != "" {
-						input := ParseMessageInput{
-							Message:   event.Message.Text,
-							SenderID:  event.Sender.ID,
-							TimeStamp: event.Timestamp}
-						s.ResponseSink() <- s.ParseMessage(&input)
+					input := messagingInput(event)
+					if input == nil {
+						continue
+					}
+					// Already queued events are deduplicated when
+					// whole callback is delivered again.
+					if err := s.Enqueue(input); err != nil {
+						return nil, err
 					}
 				}
 			}
@@ -28,3 +32,101 @@ func makeMessagesEndPoint(s Service) endpoint.Endpoint {
 		return "not supported", nil
 	}
 }
+
+// referralPayloads are refs of m.me links which are run as payloads.
+// Anybody can craft such link, so only payloads safe to be triggered
+// without asking user are allowed.
+var referralPayloads = map[string]bool{
+	PayloadGetStarted: true,
+}
+
+// messagingInput converts Messenger event to input of service, returns nil
+// for events which don't need any reaction (read and delivery receipts).
+func messagingInput(event *m.Messaging) *ParseMessageInput {
+	input := &ParseMessageInput{
+		Channel:   Facebook,
+		SenderID:  event.Sender.ID,
+		TimeStamp: event.Timestamp,
+	}
+	switch {
+	case event.Postback != nil:
+		input.MID = event.Postback.MID
+		input.Message = event.Postback.Title
+		input.Payload = event.Postback.Payload
+	case event.Referral != nil:
+		if !referralPayloads[event.Referral.Ref] {
+			return nil
+		}
+		input.Payload = event.Referral.Ref
+	case event.Message.QuickReply != nil:
+		input.MID = event.Message.MID
+		input.Message = event.Message.Text
+		input.Payload = event.Message.QuickReply.Payload
+	case event.Message.Text != "" || event.Message.Attachments != nil:
+		// Attachments without text are answered with help.
+		input.MID = event.Message.MID
+		input.Message = event.Message.Text
+	default:
+		return nil
+	}
+	if input.MID == "" {
+		// Postbacks and referrals of older API versions don't carry message ID.
+		input.MID = fmt.Sprintf("%s-%d", event.Sender.ID, event.Timestamp)
+	}
+	return input
+}
+
+func makeTelegramEndPoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		update, ok := request.(m.TelegramUpdate)
+		if !ok {
+			return "not supported", nil
+		}
+		if cb := update.CallbackQuery; cb != nil {
+			if cb.Message != nil {
+				input := ParseMessageInput{
+					Channel:  Telegram,
+					MID:      strconv.Itoa(update.UpdateID),
+					Payload:  cb.Data,
+					SenderID: strconv.FormatInt(cb.Message.Chat.ID, 10)}
+				if err := s.Enqueue(&input); err != nil {
+					return nil, err
+				}
+			}
+			// Clients show loading indicator until query is answered.
+			return &m.TelegramAnswerCallbackQuery{Method: "answerCallbackQuery", CallbackQueryID: cb.ID}, nil
+		}
+		if msg := update.Message; msg != nil && msg.Text != "" {
+			input := ParseMessageInput{
+				Channel:  Telegram,
+				MID:      strconv.Itoa(update.UpdateID),
+				Message:  telegramCommand(msg.Text),
+				SenderID: strconv.FormatInt(msg.Chat.ID, 10),
+				// Telegram reports seconds, Messenger milliseconds.
+				TimeStamp: msg.Date * 1000}
+			if err := s.Enqueue(&input); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+}
+
+// telegramCommand turns bot commands like "/start" or "/set@Bot time 8:30"
+// into plain commands understood by all channels.
+func telegramCommand(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return text
+	}
+	text = text[1:]
+	command, rest := text, ""
+	if i := strings.IndexAny(text, " \n"); i >= 0 {
+		command, rest = text[:i], text[i:]
+	}
+	if i := strings.Index(command, "@"); i >= 0 {
+		command = command[:i]
+	}
+	// Telegram commands can't contain spaces, accept "/set_time 8:30" as well.
+	return strings.Replace(command, "_", " ", -1) + rest
+}
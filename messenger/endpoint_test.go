@@ -0,0 +1,196 @@
+package messenger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	m "github.com/jozuenoon/biblia2y/models"
+)
+
+// fakeService records queued messages.
+type fakeService struct {
+	inputs []ParseMessageInput
+	err    error
+}
+
+func (s *fakeService) ParseMessage(in *ParseMessageInput) *ParseMessageOutput {
+	return &ParseMessageOutput{SenderID: in.SenderID}
+}
+
+func (s *fakeService) Recover() error { return nil }
+
+func (s *fakeService) Enqueue(in *ParseMessageInput) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.inputs = append(s.inputs, *in)
+	return nil
+}
+
+func Test_telegramCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"start", "start"},
+		{"/start", "start"},
+		{" /help ", "help"},
+		{"/start@Biblia2yBot", "start"},
+		{"/set_time 8:30", "set time 8:30"},
+		{"/set@Biblia2yBot time 8:30", "set time 8:30"},
+		{"J 3,16", "J 3,16"},
+	}
+	for _, tt := range tests {
+		if got := telegramCommand(tt.text); got != tt.want {
+			t.Errorf("telegramCommand(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMakeTelegramHandler(t *testing.T) {
+	update := `{"update_id":1,"message":{"message_id":7,"from":{"id":42,"first_name":"Jan"},"chat":{"id":42,"type":"private"},"date":1551427200,"text":"/start"}}`
+	callback := `{"update_id":2,"callback_query":{"id":"77","from":{"id":42},"message":{"message_id":8,"chat":{"id":42},"date":1551427200},"data":"READ"}}`
+
+	tests := []struct {
+		name       string
+		update     string
+		configured string
+		secret     string
+		wantStatus int
+		wantBody   string
+		want       []ParseMessageInput
+	}{
+		{"valid", update, "secret", "secret", 200, "Got your message", []ParseMessageInput{{Channel: Telegram, MID: "1", SenderID: "42", TimeStamp: 1551427200000, Message: "start"}}},
+		{"callback answered", callback, "secret", "secret", 200, `{"method":"answerCallbackQuery","callback_query_id":"77"}` + "\n", []ParseMessageInput{{Channel: Telegram, MID: "2", SenderID: "42", Payload: "READ"}}},
+		{"wrong secret", update, "secret", "other", 401, "", nil},
+		{"secret not configured", update, "", "", 401, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{}
+			h := MakeTelegramHandler(svc, log.NewNopLogger(), tt.configured)
+
+			req := httptest.NewRequest("POST", "/telegram", strings.NewReader(tt.update))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(telegramSecretHeader, tt.secret)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if !reflect.DeepEqual(svc.inputs, tt.want) {
+				t.Errorf("inputs = %+v, want %+v", svc.inputs, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeHandler_signature(t *testing.T) {
+	const secret = "test_app_secret"
+	message := []ParseMessageInput{{Channel: Facebook, MID: "m_Zk2nYt6jC9wqY0d9H2WmNsd4Y0bN2Z3s", SenderID: "2171231386235789", TimeStamp: 1551427200001, Message: "start"}}
+
+	tests := []struct {
+		name       string
+		payload    string
+		signature  string
+		wantStatus int
+		want       []ParseMessageInput
+	}{
+		{"valid", "message.json", "sha256=c3855ae51fd9f3c92ff477f5dea561b86a83662484da5cf09e79cbc4944dfcef", 200, message},
+		{"valid unicode", "unicode.json", "sha256=e6d92c2cad939f7227191ea270d6601dc665e6dbaba2dff3b8fc8cebbcea3e18", 200,
+			[]ParseMessageInput{{Channel: Facebook, MID: "m_Qw8aX2sT1nVh5jK0pL3mZr7cYb4dE6fG", SenderID: "2171231386235789", TimeStamp: 1551427260321, Message: "set timezone Europe/Warsaw łódź"}}},
+		{"upper case hex", "message.json", "sha256=C3855AE51FD9F3C92FF477F5DEA561B86A83662484DA5CF09E79CBC4944DFCEF", 200, message},
+		{"signature of other payload", "message.json", "sha256=e6d92c2cad939f7227191ea270d6601dc665e6dbaba2dff3b8fc8cebbcea3e18", 403, nil},
+		{"sha1 signature", "message.json", "sha1=0c4d5cd3ef1d8d6d1f9d4a0a6b3a8b1b2c3d4e5f", 401, nil},
+		{"malformed", "message.json", "sha256=not-hex", 401, nil},
+		{"missing", "message.json", "", 401, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := ioutil.ReadFile("testdata/" + tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			svc := &fakeService{}
+			h := MakeHandler(svc, log.NewNopLogger(), "token", secret)
+
+			req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.signature != "" {
+				req.Header.Set(signatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(svc.inputs, tt.want) {
+				t.Errorf("inputs = %+v, want %+v", svc.inputs, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeHandler_queueFull(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/message.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &fakeService{err: ErrInboxFull}
+	h := MakeHandler(svc, log.NewNopLogger(), "token", "test_app_secret")
+
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signatureHeader, "sha256=c3855ae51fd9f3c92ff477f5dea561b86a83662484da5cf09e79cbc4944dfcef")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != 503 {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func Test_messagingInput(t *testing.T) {
+	sender := m.User{ID: "1234"}
+	tests := []struct {
+		name  string
+		event m.Messaging
+		want  *ParseMessageInput
+	}{
+		{"text", m.Messaging{Sender: sender, Timestamp: 10, Message: m.Message{MID: "m1", Text: "info"}},
+			&ParseMessageInput{Channel: Facebook, SenderID: "1234", TimeStamp: 10, MID: "m1", Message: "info"}},
+		{"quick reply", m.Messaging{Sender: sender, Timestamp: 10, Message: m.Message{MID: "m2", Text: "Next day", QuickReply: &struct {
+			Payload string `json:"payload,omitempty"`
+		}{Payload: PayloadNextDay}}},
+			&ParseMessageInput{Channel: Facebook, SenderID: "1234", TimeStamp: 10, MID: "m2", Message: "Next day", Payload: PayloadNextDay}},
+		{"postback", m.Messaging{Sender: sender, Timestamp: 10, Postback: &m.Postback{MID: "m3", Title: "Pause", Payload: PayloadPauseWeek}},
+			&ParseMessageInput{Channel: Facebook, SenderID: "1234", TimeStamp: 10, MID: "m3", Message: "Pause", Payload: PayloadPauseWeek}},
+		{"postback without mid", m.Messaging{Sender: sender, Timestamp: 10, Postback: &m.Postback{Payload: PayloadGetStarted}},
+			&ParseMessageInput{Channel: Facebook, SenderID: "1234", TimeStamp: 10, MID: "1234-10", Payload: PayloadGetStarted}},
+		{"referral", m.Messaging{Sender: sender, Timestamp: 10, Referral: &m.Referral{Ref: PayloadGetStarted, Source: "SHORTLINK"}},
+			&ParseMessageInput{Channel: Facebook, SenderID: "1234", TimeStamp: 10, MID: "1234-10", Payload: PayloadGetStarted}},
+		{"referral to stop", m.Messaging{Sender: sender, Timestamp: 10, Referral: &m.Referral{Ref: PayloadStopConfirm, Source: "SHORTLINK"}}, nil},
+		{"referral to next day", m.Messaging{Sender: sender, Timestamp: 10, Referral: &m.Referral{Ref: PayloadNextDay, Source: "SHORTLINK"}}, nil},
+		{"attachment", m.Messaging{Sender: sender, Timestamp: 10, Message: m.Message{MID: "m4", Attachments: &[]m.Attachment{{Type: "image"}}}},
+			&ParseMessageInput{Channel: Facebook, SenderID: "1234", TimeStamp: 10, MID: "m4"}},
+		{"read", m.Messaging{Sender: sender, Timestamp: 10, Read: &m.Read{Watermark: 9}}, nil},
+		{"delivery", m.Messaging{Sender: sender, Timestamp: 10, Delivery: &m.Delivery{MIDs: []string{"m1"}, Watermark: 9}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messagingInput(&tt.event); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messagingInput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
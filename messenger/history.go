@@ -0,0 +1,181 @@
+package messenger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const historyPrefix = "history/"
+
+// HistoryEntry records delivery and confirmation of single plan day.
+type HistoryEntry struct {
+	_msgpack struct{} `msgpack:",omitempty"`
+	Day      int
+	// First acknowledged delivery of the day.
+	SentAt time.Time
+	// Set when user confirmed reading, zero otherwise.
+	ConfirmedAt time.Time
+}
+
+func historyKey(senderID string, day int) []byte {
+	return []byte(fmt.Sprintf("%s%s/%09d", historyPrefix, senderID, day))
+}
+
+func getHistoryEntry(senderID string, day int, db *leveldb.DB) (*HistoryEntry, error) {
+	data, err := db.Get(historyKey(senderID, day), nil)
+	if err == leveldb.ErrNotFound {
+		return &HistoryEntry{Day: day}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h HistoryEntry
+	if err := Unmarshal(data, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// recordSent adds history of delivered day to batch, days
+// delivered again keep time of the first delivery.
+func recordSent(batch *leveldb.Batch, senderID string, day int, at time.Time, db *leveldb.DB) error {
+	h, err := getHistoryEntry(senderID, day, db)
+	if err != nil {
+		return err
+	}
+	if h.SentAt.IsZero() {
+		h.SentAt = at
+	}
+	data, err := Marshal(h)
+	if err != nil {
+		return err
+	}
+	batch.Put(historyKey(senderID, day), data)
+	return nil
+}
+
+// recordConfirmed adds confirmation of day to batch, returns
+// false if the day was confirmed before.
+func recordConfirmed(batch *leveldb.Batch, senderID string, day int, at time.Time, db *leveldb.DB) (bool, error) {
+	h, err := getHistoryEntry(senderID, day, db)
+	if err != nil {
+		return false, err
+	}
+	if !h.ConfirmedAt.IsZero() {
+		return false, nil
+	}
+	h.ConfirmedAt = at
+	data, err := Marshal(h)
+	if err != nil {
+		return false, err
+	}
+	batch.Put(historyKey(senderID, day), data)
+	return true, nil
+}
+
+// GetHistory returns reading history of user ordered by day.
+func GetHistory(senderID string, db *leveldb.DB) ([]*HistoryEntry, error) {
+	iter := db.NewIterator(util.BytesPrefix([]byte(historyPrefix+senderID+"/")), nil)
+	defer iter.Release()
+	var history []*HistoryEntry
+	for iter.Next() {
+		var h HistoryEntry
+		if err := Unmarshal(iter.Value(), &h); err != nil {
+			return nil, err
+		}
+		history = append(history, &h)
+	}
+	return history, iter.Error()
+}
+
+// deleteHistory drops reading history of user.
+func deleteHistory(senderID string, db *leveldb.DB) error {
+	iter := db.NewIterator(util.BytesPrefix([]byte(historyPrefix+senderID+"/")), nil)
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return db.Write(batch, nil)
+}
+
+// readingStats summarises reading history.
+type readingStats struct {
+	Confirmed int
+	PlanDays  int
+	// Percentage of plan days confirmed as read.
+	Completion float64
+	// Consecutive calendar days with confirmed reading, current one
+	// is still alive if the last confirmation was today or yesterday.
+	CurrentStreak int
+	LongestStreak int
+	// Deliveries since start which weren't confirmed.
+	Behind int
+}
+
+// computeStats calculates statistics of history, calendar days are
+// taken in loc. Started is time of subscription, zero if unknown.
+func computeStats(history []*HistoryEntry, planDays int, started, now time.Time, loc *time.Location) readingStats {
+	st := readingStats{PlanDays: planDays}
+
+	// Calendar days with at least one confirmation.
+	confirmedDates := make(map[time.Time]bool)
+	var first time.Time
+	for _, h := range history {
+		if !h.SentAt.IsZero() && (first.IsZero() || h.SentAt.Before(first)) {
+			first = h.SentAt
+		}
+		if h.ConfirmedAt.IsZero() {
+			continue
+		}
+		st.Confirmed++
+		confirmedDates[calendarDay(h.ConfirmedAt, loc)] = true
+	}
+	if planDays > 0 {
+		st.Completion = 100 * float64(st.Confirmed) / float64(planDays)
+	}
+
+	today := calendarDay(now, loc)
+	for date := range confirmedDates {
+		// Count only from the first day of every streak.
+		if confirmedDates[date.AddDate(0, 0, -1)] {
+			continue
+		}
+		n := 1
+		for confirmedDates[date.AddDate(0, 0, n)] {
+			n++
+		}
+		if n > st.LongestStreak {
+			st.LongestStreak = n
+		}
+		last := date.AddDate(0, 0, n-1)
+		if last.Equal(today) || last.Equal(today.AddDate(0, 0, -1)) {
+			st.CurrentStreak = n
+		}
+	}
+
+	if started.IsZero() {
+		// Users subscribed before history was kept.
+		started = first
+	}
+	if !started.IsZero() {
+		passed := int(today.Sub(calendarDay(started, loc))/(24*time.Hour)) + 1
+		if behind := passed - st.Confirmed; behind > 0 {
+			st.Behind = behind
+		}
+	}
+	return st
+}
+
+// calendarDay returns midnight of t in loc, expressed in UTC so dates
+// can be compared and shifted without DST surprises.
+func calendarDay(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,120 @@
+package messenger
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_computeStats(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2019, 3, d, 20, 0, 0, 0, time.UTC)
+	}
+	entry := func(planDay, sent, confirmed int) *HistoryEntry {
+		h := &HistoryEntry{Day: planDay, SentAt: day(sent).Add(-12 * time.Hour)}
+		if confirmed > 0 {
+			h.ConfirmedAt = day(confirmed)
+		}
+		return h
+	}
+
+	tests := []struct {
+		name    string
+		history []*HistoryEntry
+		started time.Time
+		now     time.Time
+		want    readingStats
+	}{
+		{"empty", nil, day(1), day(1), readingStats{PlanDays: 100, Behind: 1}},
+		{"every day", []*HistoryEntry{entry(0, 1, 1), entry(1, 2, 2), entry(2, 3, 3)}, day(1), day(3),
+			readingStats{Confirmed: 3, PlanDays: 100, Completion: 3, CurrentStreak: 3, LongestStreak: 3}},
+		{"streak alive until end of next day", []*HistoryEntry{entry(0, 1, 1), entry(1, 2, 2)}, day(1), day(3),
+			readingStats{Confirmed: 2, PlanDays: 100, Completion: 2, CurrentStreak: 2, LongestStreak: 2, Behind: 1}},
+		{"broken streak", []*HistoryEntry{entry(0, 1, 1), entry(1, 2, 2), entry(2, 3, 0), entry(3, 4, 5)}, day(1), day(5),
+			readingStats{Confirmed: 3, PlanDays: 100, Completion: 3, CurrentStreak: 1, LongestStreak: 2, Behind: 2}},
+		{"two days read at once count once", []*HistoryEntry{entry(0, 1, 2), entry(1, 2, 2)}, day(1), day(2),
+			readingStats{Confirmed: 2, PlanDays: 100, Completion: 2, CurrentStreak: 1, LongestStreak: 1}},
+		{"lost streak", []*HistoryEntry{entry(0, 1, 1)}, day(1), day(4),
+			readingStats{Confirmed: 1, PlanDays: 100, Completion: 1, LongestStreak: 1, Behind: 3}},
+		{"unknown start uses first delivery", []*HistoryEntry{entry(0, 2, 2)}, time.Time{}, day(3),
+			readingStats{Confirmed: 1, PlanDays: 100, Completion: 1, CurrentStreak: 1, LongestStreak: 1, Behind: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeStats(tt.history, 100, tt.started, tt.now, time.UTC); got != tt.want {
+				t.Errorf("computeStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_computeStatsLocalDays(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 23:30 UTC is next day in Warsaw, DST starts on 31st of March.
+	history := []*HistoryEntry{
+		{Day: 0, ConfirmedAt: time.Date(2019, 3, 30, 22, 30, 0, 0, time.UTC)},
+		{Day: 1, ConfirmedAt: time.Date(2019, 3, 30, 23, 30, 0, 0, time.UTC)},
+		{Day: 2, ConfirmedAt: time.Date(2019, 4, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	st := computeStats(history, 10, time.Date(2019, 3, 29, 12, 0, 0, 0, time.UTC), time.Date(2019, 4, 1, 8, 0, 0, 0, time.UTC), loc)
+	if st.CurrentStreak != 3 || st.LongestStreak != 3 || st.Behind != 1 {
+		t.Errorf("computeStats() = %+v", st)
+	}
+}
+
+func TestService_requireConfirm(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	user := &User{SenderID: "1", CurrentDay: 3, Zone: "UTC", RequireConfirm: true}
+	if err := PutUserData(user, s.DB); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.outbox.Enqueue("1", nil, &OutboxEntry{SenderID: "1", Day: 3, NextDay: 4, Parts: []string{"day 3"}}); err != nil {
+		t.Fatal(err)
+	}
+	s.outbox.deliver("1", time.Now())
+
+	got, err := GetUserData("1", s.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 3 || got.LastDeliveredDay != 3 {
+		t.Errorf("CurrentDay = %d LastDeliveredDay = %d, want 3 before confirmation", got.CurrentDay, got.LastDeliveredDay)
+	}
+	// Delivery slot is normally recorded by scheduled task.
+	got.LastDeliveredAt = time.Now()
+	if err := PutUserData(got, s.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	replies := []string{"Great! Day 3 is marked as read.", "Day 3 was already marked as read."}
+	for _, want := range replies {
+		if out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Message: "read"}); out.Message[0].Text != want {
+			t.Errorf("reply = %q, want %q", out.Message[0].Text, want)
+		}
+	}
+
+	got, err = GetUserData("1", s.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 4 {
+		t.Errorf("CurrentDay = %d, want 4 after confirmation", got.CurrentDay)
+	}
+	history, err := GetHistory("1", s.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Day != 3 || history[0].SentAt.IsZero() || history[0].ConfirmedAt.IsZero() {
+		t.Errorf("unexpected history %+v", history)
+	}
+
+	// Stop removes history with user.
+	s.Stop("1")
+	if history, _ := GetHistory("1", s.DB); len(history) != 0 {
+		t.Errorf("history not deleted: %+v", history)
+	}
+}
@@ -0,0 +1,193 @@
+package messenger
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	defaultInboxWorkers   = 4
+	defaultInboxQueueSize = 100
+	// Number of recently seen message IDs remembered for deduplication.
+	inboxSeenSize = 10000
+)
+
+// ErrInboxFull is returned when incoming message can't be queued,
+// webhook replies with 503 so channel delivers it again later.
+var ErrInboxFull error = &statusError{status: http.StatusServiceUnavailable, msg: "inbound queue is full"}
+
+// InboxOptions of inbound queue, zero values are replaced with defaults.
+type InboxOptions struct {
+	// Number of workers processing messages.
+	Workers int
+	// Number of messages queued for every worker.
+	QueueSize int
+}
+
+func (o InboxOptions) withDefaults() InboxOptions {
+	if o.Workers <= 0 {
+		o.Workers = defaultInboxWorkers
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultInboxQueueSize
+	}
+	return o
+}
+
+// InboxStats is snapshot of inbound queue metrics.
+type InboxStats struct {
+	Workers int
+	// Messages waiting in worker queues.
+	Queued int64
+	// Messages being processed by workers.
+	InFlight int64
+	// Messages accepted to queue.
+	Received int64
+	// Redelivered messages which were ignored.
+	Duplicates int64
+	// Messages rejected because queue was full.
+	Rejected int64
+	// Messages processed by workers.
+	Processed int64
+}
+
+// Inbox queues incoming messages, so webhook can acknowledge them at once.
+// Messages of single sender are processed by the same worker in order
+// they were received. Messages redelivered by channel are dropped.
+type Inbox struct {
+	queues  []chan *ParseMessageInput
+	process func(*ParseMessageInput)
+	log     log.Logger
+
+	mu sync.Mutex
+	// Recently queued message IDs, ring buffer backs eviction from map.
+	seen     map[string]bool
+	seenRing []string
+	seenNext int
+
+	queued     int64
+	inFlight   int64
+	received   int64
+	duplicates int64
+	rejected   int64
+	processed  int64
+}
+
+func NewInbox(opts InboxOptions, process func(*ParseMessageInput), log log.Logger) *Inbox {
+	opts = opts.withDefaults()
+	i := &Inbox{
+		queues:   make([]chan *ParseMessageInput, opts.Workers),
+		process:  process,
+		log:      log,
+		seen:     make(map[string]bool),
+		seenRing: make([]string, inboxSeenSize),
+	}
+	for n := range i.queues {
+		i.queues[n] = make(chan *ParseMessageInput, opts.QueueSize)
+	}
+	return i
+}
+
+// Push queues message without blocking. Duplicates are silently dropped,
+// ErrInboxFull is returned when worker queue is full.
+func (i *Inbox) Push(in *ParseMessageInput) error {
+	key := dedupeKey(in)
+	if key != "" && !i.remember(key) {
+		atomic.AddInt64(&i.duplicates, 1)
+		i.log.Log("msg", "duplicate message", "user_id", in.SenderID, "mid", in.MID)
+		return nil
+	}
+	select {
+	case i.queues[i.shard(in)] <- in:
+		atomic.AddInt64(&i.queued, 1)
+		atomic.AddInt64(&i.received, 1)
+		return nil
+	default:
+		// Let redelivery of this message through.
+		if key != "" {
+			i.forget(key)
+		}
+		atomic.AddInt64(&i.rejected, 1)
+		return ErrInboxFull
+	}
+}
+
+// Run starts workers and blocks until done is closed.
+func (i *Inbox) Run(done <-chan struct{}) {
+	wg := sync.WaitGroup{}
+	for _, queue := range i.queues {
+		wg.Add(1)
+		go func(queue <-chan *ParseMessageInput) {
+			defer wg.Done()
+			i.work(queue, done)
+		}(queue)
+	}
+	wg.Wait()
+}
+
+func (i *Inbox) work(queue <-chan *ParseMessageInput, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case in := <-queue:
+			atomic.AddInt64(&i.queued, -1)
+			atomic.AddInt64(&i.inFlight, 1)
+			i.process(in)
+			atomic.AddInt64(&i.inFlight, -1)
+			atomic.AddInt64(&i.processed, 1)
+		}
+	}
+}
+
+func (i *Inbox) Stats() InboxStats {
+	return InboxStats{
+		Workers:    len(i.queues),
+		Queued:     atomic.LoadInt64(&i.queued),
+		InFlight:   atomic.LoadInt64(&i.inFlight),
+		Received:   atomic.LoadInt64(&i.received),
+		Duplicates: atomic.LoadInt64(&i.duplicates),
+		Rejected:   atomic.LoadInt64(&i.rejected),
+		Processed:  atomic.LoadInt64(&i.processed),
+	}
+}
+
+func (i *Inbox) shard(in *ParseMessageInput) int {
+	h := fnv.New32a()
+	h.Write([]byte(userID(in.Channel, in.SenderID)))
+	return int(h.Sum32() % uint32(len(i.queues)))
+}
+
+// remember records message ID, returns false if it was already seen.
+func (i *Inbox) remember(key string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.seen[key] {
+		return false
+	}
+	if old := i.seenRing[i.seenNext]; old != "" {
+		delete(i.seen, old)
+	}
+	i.seenRing[i.seenNext] = key
+	i.seenNext = (i.seenNext + 1) % len(i.seenRing)
+	i.seen[key] = true
+	return true
+}
+
+func (i *Inbox) forget(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	// Slot in ring is reused later, deleting it from map is enough.
+	delete(i.seen, key)
+}
+
+func dedupeKey(in *ParseMessageInput) string {
+	if in.MID == "" {
+		return ""
+	}
+	return in.Channel + "/" + in.MID
+}
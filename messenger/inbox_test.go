@@ -0,0 +1,98 @@
+package messenger
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestInbox_Push(t *testing.T) {
+	processed := make(chan string)
+	i := NewInbox(InboxOptions{Workers: 1, QueueSize: 2}, func(in *ParseMessageInput) {
+		processed <- in.MID
+	}, log.NewNopLogger())
+
+	steps := []struct {
+		name    string
+		mid     string
+		wantErr error
+	}{
+		{"first", "m1", nil},
+		{"second", "m2", nil},
+		{"redelivery", "m1", nil},
+		{"queue full", "m3", ErrInboxFull},
+		{"redelivery of rejected is rejected again", "m3", ErrInboxFull},
+	}
+	for _, st := range steps {
+		if err := i.Push(&ParseMessageInput{SenderID: "1", MID: st.mid}); err != st.wantErr {
+			t.Errorf("%s: Push() error = %v, want %v", st.name, err, st.wantErr)
+		}
+	}
+
+	want := InboxStats{Workers: 1, Queued: 2, Received: 2, Duplicates: 1, Rejected: 2}
+	if got := i.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go i.Run(done)
+	got := []string{<-processed, <-processed}
+	if want := []string{"m1", "m2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("processed %v, want %v", got, want)
+	}
+}
+
+func TestInbox_order(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[string][]string)
+	wg := sync.WaitGroup{}
+	i := NewInbox(InboxOptions{Workers: 3, QueueSize: 100}, func(in *ParseMessageInput) {
+		mu.Lock()
+		got[in.SenderID] = append(got[in.SenderID], in.Message)
+		mu.Unlock()
+		wg.Done()
+	}, log.NewNopLogger())
+	done := make(chan struct{})
+	defer close(done)
+	go i.Run(done)
+
+	senders := []string{"1", "2", "3", "4", "5"}
+	for n := 0; n < 20; n++ {
+		for _, sender := range senders {
+			wg.Add(1)
+			in := &ParseMessageInput{SenderID: sender, MID: fmt.Sprintf("%s.%d", sender, n), Message: fmt.Sprint(n)}
+			if err := i.Push(in); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	wg.Wait()
+
+	for _, sender := range senders {
+		for n, msg := range got[sender] {
+			if msg != fmt.Sprint(n) {
+				t.Fatalf("sender %s messages out of order: %v", sender, got[sender])
+			}
+		}
+	}
+}
+
+func TestInbox_seenIsBounded(t *testing.T) {
+	i := NewInbox(InboxOptions{Workers: 1, QueueSize: inboxSeenSize + 1}, func(*ParseMessageInput) {}, log.NewNopLogger())
+	for n := 0; n <= inboxSeenSize; n++ {
+		if err := i.Push(&ParseMessageInput{MID: fmt.Sprint(n)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(i.seen) != inboxSeenSize {
+		t.Errorf("remembered %d message IDs, want %d", len(i.seen), inboxSeenSize)
+	}
+	// Oldest ID was evicted, so it's accepted again.
+	if !i.remember("/0") {
+		t.Error("oldest message ID wasn't evicted")
+	}
+}
@@ -0,0 +1,29 @@
+package messenger
+
+import (
+	m "github.com/jozuenoon/biblia2y/models"
+)
+
+const greeting = "Daily Bible reading plan. Tap Get Started and verses will come every day at chosen time."
+
+// MessengerProfile returns Get Started button, greeting and persistent
+// menu of the page, menu items mirror typed commands.
+func MessengerProfile() *m.MessengerProfile {
+	return &m.MessengerProfile{
+		GetStarted: &m.GetStarted{Payload: PayloadGetStarted},
+		Greeting: []m.Greeting{
+			{Locale: "default", Text: greeting},
+		},
+		PersistentMenu: []m.PersistentMenu{{
+			Locale: "default",
+			// Items mirror start, show day and stop commands, Messenger
+			// allows 3 items without nesting. Info and help are typed,
+			// help is sent for any unknown message.
+			CallToActions: []m.MenuItem{
+				{Type: "postback", Title: "Start", Payload: PayloadGetStarted},
+				{Type: "postback", Title: "Today's reading", Payload: PayloadTodayReferences},
+				{Type: "postback", Title: "Unsubscribe", Payload: PayloadStop},
+			},
+		}},
+	}
+}
@@ -0,0 +1,32 @@
+package messenger
+
+import "testing"
+
+func TestMessengerProfile_menu(t *testing.T) {
+	menu := MessengerProfile().PersistentMenu
+	if len(menu) != 1 {
+		t.Fatalf("menus = %d, want 1", len(menu))
+	}
+	// Messenger accepts 3 top level items and no nested ones.
+	items := menu[0].CallToActions
+	if len(items) > 3 {
+		t.Errorf("menu has %d items, want at most 3", len(items))
+	}
+	payloads := make(map[string]bool)
+	for _, item := range items {
+		if item.Type != "postback" || item.Payload == "" || len(item.CallToActions) != 0 {
+			t.Errorf("unexpected menu item %+v", item)
+		}
+		payloads[item.Payload] = true
+	}
+	// Subscription can be started from menu, reading is shown
+	// before the first delivery too.
+	for _, payload := range []string{PayloadGetStarted, PayloadTodayReferences, PayloadStop} {
+		if !payloads[payload] {
+			t.Errorf("menu misses %s item", payload)
+		}
+	}
+	if payloads[PayloadRepeatToday] {
+		t.Errorf("menu has %s item, it fails before the first delivery", PayloadRepeatToday)
+	}
+}
@@ -0,0 +1,381 @@
+package messenger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jozuenoon/biblia2y/poster"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	outboxPrefix = "outbox/"
+
+	outboxPending = "pending"
+	outboxFailed  = "failed"
+
+	// Entry is marked as failed after that many attempts or after
+	// permanent error, it's revived when next delivery of the same
+	// day is scheduled.
+	outboxMaxAttempts = 10
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+	// Number of users served concurrently.
+	outboxWorkers = 8
+	// Pending entries are checked at least that often.
+	outboxPollInterval = time.Minute
+)
+
+// OutboxEntry is single scheduled delivery persisted until
+// all of its parts are acknowledged by transport.
+type OutboxEntry struct {
+	_msgpack struct{} `msgpack:",omitempty"`
+	SenderID string
+	// Plan day delivered with this entry.
+	Day int
+	// Plan day user is moved to after successful delivery.
+	NextDay int
+	Parts   []string
+	// Quick replies attached to the last part.
+	QuickReplies []poster.QuickReply
+	// Number of parts already acknowledged.
+	Sent        int
+	Attempts    int
+	Status      string
+	NextAttempt time.Time
+	LastError   string
+	CreatedAt   time.Time
+}
+
+func outboxKey(senderID string, day int) []byte {
+	return []byte(fmt.Sprintf("%s%s/%09d", outboxPrefix, senderID, day))
+}
+
+func (e *OutboxEntry) key() []byte {
+	return outboxKey(e.SenderID, e.Day)
+}
+
+// Outbox persists scheduled deliveries in LevelDB and drains them
+// with retries, so delivery survives send errors and restarts.
+type Outbox struct {
+	db   *leveldb.DB
+	psvc poster.Service
+	log  log.Logger
+	wake chan struct{}
+	// Called when recipient can't be messaged anymore, e.g. blocked page.
+	Unreachable func(senderID string, err error)
+	// Users currently being served, one worker per user keeps parts in order.
+	busyLock sync.Mutex
+	busy     map[string]bool
+	// Serialises read and write of user records by outbox.
+	userLock sync.Mutex
+}
+
+func NewOutbox(db *leveldb.DB, psvc poster.Service, log log.Logger) *Outbox {
+	return &Outbox{
+		db:   db,
+		psvc: psvc,
+		log:  log,
+		wake: make(chan struct{}, 1),
+		busy: make(map[string]bool),
+	}
+}
+
+// Enqueue persists entries and returns number of queued entries.
+// Entries which are already pending are left untouched, failed
+// entries are revived. Update, if not nil, is applied to fresh user
+// record which is saved in the same batch, so caller changes only
+// fields it owns and progress of concurrent delivery isn't lost.
+func (o *Outbox) Enqueue(senderID string, update func(*User), entries ...*OutboxEntry) (int, error) {
+	o.userLock.Lock()
+	defer o.userLock.Unlock()
+
+	batch := new(leveldb.Batch)
+	queued := 0
+	for _, e := range entries {
+		existing, err := o.get(e.key())
+		if err == nil && existing.Status == outboxPending {
+			o.log.Log("msg", "delivery already pending", "user_id", e.SenderID, "day", e.Day)
+			continue
+		}
+		e.Status = outboxPending
+		e.Attempts = 0
+		e.Sent = 0
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now()
+		}
+		data, err := Marshal(e)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal outbox entry %s", err.Error())
+		}
+		batch.Put(e.key(), data)
+		queued++
+	}
+	if update != nil {
+		userData, err := GetUserData(senderID, o.db)
+		if err != nil {
+			return 0, err
+		}
+		update(userData)
+		data, err := Marshal(userData)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal data %s", err.Error())
+		}
+		batch.Put([]byte(userData.SenderID), data)
+	}
+
+	if err := o.db.Write(batch, nil); err != nil {
+		return 0, err
+	}
+	o.notify()
+	return queued, nil
+}
+
+// Entries returns outbox entries of user ordered by day.
+func (o *Outbox) Entries(senderID string) ([]*OutboxEntry, error) {
+	iter := o.db.NewIterator(util.BytesPrefix([]byte(outboxPrefix+senderID+"/")), nil)
+	defer iter.Release()
+	var entries []*OutboxEntry
+	for iter.Next() {
+		var e OutboxEntry
+		if err := Unmarshal(iter.Value(), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, iter.Error()
+}
+
+// Cancel drops all entries of user.
+func (o *Outbox) Cancel(senderID string) error {
+	iter := o.db.NewIterator(util.BytesPrefix([]byte(outboxPrefix+senderID+"/")), nil)
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return o.db.Write(batch, nil)
+}
+
+func (o *Outbox) get(key []byte) (*OutboxEntry, error) {
+	data, err := o.db.Get(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	var e OutboxEntry
+	if err := Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (o *Outbox) put(e *OutboxEntry) error {
+	data, err := Marshal(e)
+	if err != nil {
+		return err
+	}
+	return o.db.Put(e.key(), data, nil)
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run drains pending entries until done is closed.
+func (o *Outbox) Run(done <-chan struct{}) {
+	sem := make(chan struct{}, outboxWorkers)
+	for {
+		next := o.drain(time.Now(), sem)
+
+		wait := outboxPollInterval
+		if !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-o.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// drain starts worker for every user with entries due at now,
+// returns earliest retry time of entries which are not due yet.
+func (o *Outbox) drain(now time.Time, sem chan struct{}) time.Time {
+	var next time.Time
+	due := make(map[string]bool)
+
+	iter := o.db.NewIterator(util.BytesPrefix([]byte(outboxPrefix)), nil)
+	for iter.Next() {
+		var e OutboxEntry
+		if err := Unmarshal(iter.Value(), &e); err != nil {
+			o.log.Log("msg", "failed to unmarshall outbox entry", "key", iter.Key(), "err", err)
+			continue
+		}
+		if e.Status != outboxPending {
+			continue
+		}
+		if e.NextAttempt.After(now) {
+			if next.IsZero() || e.NextAttempt.Before(next) {
+				next = e.NextAttempt
+			}
+			continue
+		}
+		due[e.SenderID] = true
+	}
+	iter.Release()
+
+	for senderID := range due {
+		if !o.acquire(senderID) {
+			continue
+		}
+		sem <- struct{}{}
+		go func(senderID string) {
+			defer func() { <-sem }()
+			defer o.release(senderID)
+			o.deliver(senderID, now)
+		}(senderID)
+	}
+	return next
+}
+
+func (o *Outbox) acquire(senderID string) bool {
+	o.busyLock.Lock()
+	defer o.busyLock.Unlock()
+	if o.busy[senderID] {
+		return false
+	}
+	o.busy[senderID] = true
+	return true
+}
+
+func (o *Outbox) release(senderID string) {
+	o.busyLock.Lock()
+	delete(o.busy, senderID)
+	o.busyLock.Unlock()
+	// Entries of that user could be added in the meantime.
+	o.notify()
+}
+
+// deliver sends due entries of single user in day order,
+// stops at first failure so days are never reordered.
+func (o *Outbox) deliver(senderID string, now time.Time) {
+	entries, err := o.Entries(senderID)
+	if err != nil {
+		o.log.Log("msg", "failed to read outbox", "user_id", senderID, "err", err)
+		return
+	}
+	for _, e := range entries {
+		if e.Status != outboxPending {
+			continue
+		}
+		if e.NextAttempt.After(now) {
+			return
+		}
+		if err := o.send(e); err != nil {
+			return
+		}
+	}
+}
+
+// send delivers remaining parts of entry and advances user plan
+// once all parts are acknowledged.
+func (o *Outbox) send(e *OutboxEntry) error {
+	if _, err := GetUserData(e.SenderID, o.db); err != nil {
+		// User unsubscribed in the meantime.
+		o.log.Log("msg", "dropping delivery of unknown user", "user_id", e.SenderID, "day", e.Day, "err", err)
+		return o.db.Delete(e.key(), nil)
+	}
+
+	for e.Sent < len(e.Parts) {
+		msg := poster.Message{Text: e.Parts[e.Sent]}
+		if e.Sent == len(e.Parts)-1 {
+			msg.QuickReplies = e.QuickReplies
+		}
+		err := o.psvc.Send(e.SenderID, []poster.Message{msg}, poster.Update)
+		if err != nil {
+			if poster.IsRecipientUnavailable(err) && o.Unreachable != nil {
+				o.log.Log("msg", "recipient unavailable", "user_id", e.SenderID, "day", e.Day, "err", err)
+				o.Unreachable(e.SenderID, err)
+				return err
+			}
+			o.retry(e, err)
+			return err
+		}
+		e.Sent++
+		if err := o.put(e); err != nil {
+			o.log.Log("msg", "failed to save outbox progress", "user_id", e.SenderID, "day", e.Day, "err", err)
+		}
+	}
+
+	// Sending takes a while, user could change settings in the
+	// meantime, so progress is applied to fresh record.
+	o.userLock.Lock()
+	defer o.userLock.Unlock()
+	userData, err := GetUserData(e.SenderID, o.db)
+	if err != nil {
+		o.log.Log("msg", "user unsubscribed during delivery", "user_id", e.SenderID, "day", e.Day, "err", err)
+		return o.db.Delete(e.key(), nil)
+	}
+	// Don't override day set by user in the meantime. Users who
+	// confirm reading are moved forward by confirmation only.
+	if userData.CurrentDay == e.Day && !userData.RequireConfirm {
+		userData.CurrentDay = e.NextDay
+	}
+	userData.LastDeliveredDay = e.Day
+
+	batch := new(leveldb.Batch)
+	if err := recordSent(batch, e.SenderID, e.Day, time.Now(), o.db); err != nil {
+		o.log.Log("msg", "error while reading history", "user_id", e.SenderID, "day", e.Day, "err", err)
+	}
+	data, err := Marshal(userData)
+	if err != nil {
+		return err
+	}
+	batch.Put([]byte(userData.SenderID), data)
+	batch.Delete(e.key())
+	if err := o.db.Write(batch, nil); err != nil {
+		o.log.Log("msg", "error while saving user progress", "user_id", e.SenderID, "err", err)
+		return err
+	}
+	o.log.Log("msg", "delivery acknowledged", "user_id", e.SenderID, "day", e.Day, "attempts", e.Attempts+1)
+	return nil
+}
+
+func (o *Outbox) retry(e *OutboxEntry, sendErr error) {
+	e.Attempts++
+	e.LastError = sendErr.Error()
+	e.NextAttempt = time.Now().Add(outboxBackoff(e.Attempts))
+	if e.Attempts >= outboxMaxAttempts || !poster.IsRetryable(sendErr) {
+		e.Status = outboxFailed
+	}
+	o.log.Log("msg", "delivery failed", "user_id", e.SenderID, "day", e.Day, "attempts", e.Attempts, "status", e.Status, "err", sendErr)
+	if err := o.put(e); err != nil {
+		o.log.Log("msg", "failed to save outbox entry", "user_id", e.SenderID, "day", e.Day, "err", err)
+	}
+}
+
+// outboxBackoff returns exponential delay for given attempt.
+func outboxBackoff(attempt int) time.Duration {
+	d := outboxBaseBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return d
+}
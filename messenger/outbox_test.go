@@ -0,0 +1,312 @@
+package messenger
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jozuenoon/biblia2y/poster"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// fakePoster records sent messages and fails while fail returns true.
+type fakePoster struct {
+	sent []string
+	// Recipient of every sent message.
+	to []string
+	// Quick replies of every message sent with Send.
+	replies [][]poster.QuickReply
+	fail    func(msg string) bool
+	// Error returned on failure, retryable by default.
+	err error
+	// Profile reported for every user, UTC+1 when nil.
+	profile *poster.Profile
+}
+
+func (p *fakePoster) ProcessMessages(senderID string, messages []string, kind poster.Kind) error {
+	for _, msg := range messages {
+		if p.fail != nil && p.fail(msg) {
+			if p.err != nil {
+				return p.err
+			}
+			return poster.Errors{&poster.SendError{Err: fmt.Errorf("send failed"), Class: poster.Retryable}}
+		}
+		p.sent = append(p.sent, msg)
+		p.to = append(p.to, senderID)
+	}
+	return nil
+}
+
+func (p *fakePoster) Send(senderID string, messages []poster.Message, kind poster.Kind) error {
+	for _, msg := range messages {
+		if err := p.ProcessMessages(senderID, []string{msg.Text}, kind); err != nil {
+			return err
+		}
+		p.replies = append(p.replies, msg.QuickReplies)
+	}
+	return nil
+}
+
+func (p *fakePoster) Profile(senderID string) (*poster.Profile, error) {
+	if p.profile != nil {
+		return p.profile, nil
+	}
+	return &poster.Profile{Timezone: 1}, nil
+}
+
+func (p *fakePoster) Stats() poster.Stats {
+	return poster.Stats{Sent: int64(len(p.sent))}
+}
+
+func (p *fakePoster) Close() {}
+
+func newTestDB(t *testing.T) *leveldb.DB {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestOutbox_send(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	failing := true
+	p := &fakePoster{fail: func(msg string) bool { return failing && msg == "part 2" }}
+	o := NewOutbox(db, p, log.NewNopLogger())
+
+	user := &User{SenderID: "1", CurrentDay: 5}
+	entry := &OutboxEntry{SenderID: "1", Day: 5, NextDay: 6, Parts: []string{"part 1", "part 2"}, QuickReplies: dailyQuickReplies}
+	if err := PutUserData(user, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Enqueue("1", nil, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// Second part fails, day must not advance.
+	o.deliver("1", time.Now())
+	got, err := GetUserData("1", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 5 {
+		t.Errorf("CurrentDay = %d, want 5 after failed delivery", got.CurrentDay)
+	}
+	entries, err := o.Entries("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Sent != 1 || entries[0].Attempts != 1 || entries[0].Status != outboxPending {
+		t.Fatalf("unexpected outbox state %+v", entries)
+	}
+
+	// Enqueue of pending day is ignored.
+	if queued, err := o.Enqueue("1", nil, &OutboxEntry{SenderID: "1", Day: 5, NextDay: 6, Parts: []string{"other"}}); err != nil || queued != 0 {
+		t.Fatalf("Enqueue() = %d, %v, want pending entry skipped", queued, err)
+	}
+
+	// Retry resumes from unsent part once backoff passed.
+	failing = false
+	o.deliver("1", time.Now())
+	if len(p.sent) != 1 {
+		t.Errorf("retry sent before backoff: %v", p.sent)
+	}
+	o.deliver("1", time.Now().Add(outboxBackoff(1)))
+	if !reflect.DeepEqual(p.sent, []string{"part 1", "part 2"}) {
+		t.Errorf("sent = %v", p.sent)
+	}
+	// Quick replies go with the last part only.
+	if want := [][]poster.QuickReply{nil, dailyQuickReplies}; !reflect.DeepEqual(p.replies, want) {
+		t.Errorf("quick replies = %v, want %v", p.replies, want)
+	}
+
+	got, err = GetUserData("1", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 6 || got.LastDeliveredDay != 5 {
+		t.Errorf("CurrentDay = %d LastDeliveredDay = %d, want 6 and 5", got.CurrentDay, got.LastDeliveredDay)
+	}
+	if entries, _ := o.Entries("1"); len(entries) != 0 {
+		t.Errorf("outbox not drained: %+v", entries)
+	}
+}
+
+func TestOutbox_sendKeepsUserChanges(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	p := &fakePoster{}
+	o := NewOutbox(db, p, log.NewNopLogger())
+
+	user := &User{SenderID: "1", CurrentDay: 5}
+	entry := &OutboxEntry{SenderID: "1", Day: 5, NextDay: 6, Parts: []string{"part 1", "part 2"}}
+	if err := PutUserData(user, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Enqueue("1", nil, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// User changes settings and plan day while parts are being sent.
+	p.fail = func(msg string) bool {
+		if msg != "part 2" {
+			return false
+		}
+		changed, err := GetUserData("1", db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed.CurrentDay = 20
+		changed.Translation = "pt"
+		changed.RequireConfirm = true
+		if err := PutUserData(changed, db); err != nil {
+			t.Fatal(err)
+		}
+		return false
+	}
+	o.deliver("1", time.Now())
+
+	got, err := GetUserData("1", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 20 || got.Translation != "pt" || !got.RequireConfirm {
+		t.Errorf("user changes lost: %+v", got)
+	}
+	if got.LastDeliveredDay != 5 {
+		t.Errorf("LastDeliveredDay = %d, want 5", got.LastDeliveredDay)
+	}
+}
+
+func TestOutbox_sendUnknownUser(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	p := &fakePoster{}
+	o := NewOutbox(db, p, log.NewNopLogger())
+
+	if err := PutUserData(&User{SenderID: "1"}, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Enqueue("1", nil, &OutboxEntry{SenderID: "1", Parts: []string{"part"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte("1"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	o.deliver("1", time.Now())
+	if len(p.sent) != 0 {
+		t.Errorf("sent to unknown user: %v", p.sent)
+	}
+	if entries, _ := o.Entries("1"); len(entries) != 0 {
+		t.Errorf("entry of unknown user kept: %+v", entries)
+	}
+}
+
+func TestOutbox_sendErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		wantStatus      string
+		wantUnreachable bool
+	}{
+		{"permanent", poster.Errors{&poster.SendError{Code: 190, Class: poster.Permanent}}, outboxFailed, false},
+		{"recipient unavailable", poster.Errors{&poster.SendError{Code: 551, Class: poster.RecipientUnavailable}}, outboxPending, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			defer db.Close()
+
+			p := &fakePoster{fail: func(string) bool { return true }, err: tt.err}
+			o := NewOutbox(db, p, log.NewNopLogger())
+			var unreachable bool
+			o.Unreachable = func(senderID string, err error) { unreachable = true }
+
+			if err := PutUserData(&User{SenderID: "1"}, db); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := o.Enqueue("1", nil, &OutboxEntry{SenderID: "1", Parts: []string{"part"}}); err != nil {
+				t.Fatal(err)
+			}
+			o.deliver("1", time.Now())
+
+			if unreachable != tt.wantUnreachable {
+				t.Errorf("Unreachable called = %v, want %v", unreachable, tt.wantUnreachable)
+			}
+			entries, err := o.Entries("1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Status != tt.wantStatus {
+				t.Errorf("unexpected outbox state %+v", entries)
+			}
+		})
+	}
+}
+
+func Test_outboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, outboxBaseBackoff},
+		{2, 2 * outboxBaseBackoff},
+		{3, 4 * outboxBaseBackoff},
+		{20, outboxMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempt); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func Test_isUserKey(t *testing.T) {
+	if !isUserKey([]byte("1234567")) {
+		t.Errorf("sender ID is not user key")
+	}
+	if isUserKey(outboxKey("1234567", 1)) {
+		t.Errorf("outbox key is user key")
+	}
+}
+
+func TestOutbox_EnqueueKeepsProgress(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+
+	p := &fakePoster{}
+	o := NewOutbox(db, p, log.NewNopLogger())
+
+	if err := PutUserData(&User{SenderID: "1", CurrentDay: 5}, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Enqueue("1", nil, &OutboxEntry{SenderID: "1", Day: 5, NextDay: 6, Parts: []string{"day 5"}}); err != nil {
+		t.Fatal(err)
+	}
+	o.deliver("1", time.Now())
+
+	// Next day is queued by caller who read user before delivery.
+	at := time.Now()
+	update := func(u *User) { u.LastDeliveredAt = at }
+	if _, err := o.Enqueue("1", update, &OutboxEntry{SenderID: "1", Day: 6, NextDay: 7, Parts: []string{"day 6"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetUserData("1", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 6 || got.LastDeliveredDay != 5 {
+		t.Errorf("CurrentDay = %d LastDeliveredDay = %d, want 6 and 5", got.CurrentDay, got.LastDeliveredDay)
+	}
+	if !got.LastDeliveredAt.Equal(at) {
+		t.Errorf("LastDeliveredAt = %v, want %v", got.LastDeliveredAt, at)
+	}
+}
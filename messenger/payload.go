@@ -0,0 +1,324 @@
+package messenger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jozuenoon/biblia2y/poster"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Payloads of buttons and quick replies. They are sent back
+// by channel when user taps the button, instead of typed command.
+const (
+	PayloadGetStarted  = "GET_STARTED"
+	PayloadNextDay     = "NEXT_DAY"
+	PayloadRepeatToday = "REPEAT_TODAY"
+	PayloadPauseWeek   = "PAUSE_WEEK"
+	PayloadResume      = "RESUME"
+	PayloadHelp        = "HELP"
+	PayloadInfo        = "INFO"
+	PayloadStop        = "STOP"
+	// References of today's plan day, works before the first delivery.
+	PayloadTodayReferences = "TODAY_REFERENCES"
+	// Answers of stop confirmation.
+	PayloadStopConfirm = "STOP_CONFIRM"
+	PayloadStopCancel  = "STOP_CANCEL"
+
+	// Quick replies of daily delivery.
+	PayloadRead           = "READ"
+	PayloadRemindLater    = "REMIND_LATER"
+	PayloadSkipToday      = "SKIP_TODAY"
+	PayloadShowReferences = "SHOW_REFERENCES"
+)
+
+const (
+	pauseWeek = 7 * 24 * time.Hour
+	// Delay of delivery repeated with "Send again later".
+	remindLaterDelay = 2 * time.Hour
+)
+
+// dailyQuickReplies end every daily delivery.
+var dailyQuickReplies = []poster.QuickReply{
+	{Title: "Read ✓", Payload: PayloadRead},
+	{Title: "Send again later", Payload: PayloadRemindLater},
+	{Title: "Skip today", Payload: PayloadSkipToday},
+	{Title: "Show references", Payload: PayloadShowReferences},
+}
+
+// stopQuickReplies confirm unsubscribing, it deletes reading history.
+var stopQuickReplies = []poster.QuickReply{
+	{Title: "Yes, unsubscribe", Payload: PayloadStopConfirm},
+	{Title: "No", Payload: PayloadStopCancel},
+}
+
+// dispatchPayload routes payload to service action.
+func (s *service) dispatchPayload(payload, senderID string) []poster.Message {
+	name, arg := splitPayload(payload)
+	switch name {
+	case PayloadGetStarted:
+		return textMessages(s.Start(senderID))
+	case PayloadNextDay:
+		return textMessages(s.NextDay(senderID))
+	case PayloadRepeatToday:
+		return textMessages(s.RepeatToday(senderID)...)
+	case PayloadPauseWeek:
+		return textMessages(s.Pause(senderID, pauseWeek))
+	case PayloadResume:
+		return textMessages(s.Resume(senderID))
+	case PayloadHelp:
+		return textMessages(help)
+	case PayloadInfo:
+		return textMessages(s.Info(senderID))
+	case PayloadTodayReferences:
+		return textMessages(s.TodayReferences(senderID))
+	case PayloadStop:
+		return []poster.Message{{
+			Text:         "Do you want to unsubscribe? Your schedule and reading history will be deleted.",
+			QuickReplies: stopQuickReplies,
+		}}
+	case PayloadStopConfirm:
+		return textMessages(s.Stop(senderID))
+	case PayloadStopCancel:
+		return textMessages("OK, your subscription stays as it was.")
+	case PayloadRead:
+		return textMessages(s.MarkRead(senderID))
+	case PayloadRemindLater:
+		return textMessages(s.RemindLater(senderID, remindLaterDelay))
+	case PayloadSkipToday:
+		return textMessages(s.SkipToday(senderID))
+	case PayloadShowReferences:
+		return textMessages(s.ShowReferences(senderID))
+	case PayloadChapter:
+		return s.Passage(arg, senderID)
+	}
+	s.log.Log("msg", "unknown payload", "payload", payload, "user_id", senderID)
+	return textMessages(help)
+}
+
+// NextDay delivers next day of plan right away, plan is advanced
+// when delivery is acknowledged, so regular delivery continues from there.
+func (s *service) NextDay(senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+
+	day := userData.CurrentDay
+	verses, err := getDay(s.bsvc, userData, day)
+	if err != nil {
+		s.log.Log("msg", "next day error", "user_id", senderID, "day", day, "err", err)
+		return "Sorry! Something gone wrong, can't find next day of your plan."
+	}
+	queued, err := s.outbox.Enqueue(senderID, nil, &OutboxEntry{
+		SenderID:     senderID,
+		Day:          day,
+		NextDay:      day + 1,
+		Parts:        verses,
+		QuickReplies: dailyQuickReplies,
+	})
+	if err != nil {
+		s.log.Log("msg", "error while saving delivery", "user_id", senderID, "err", err)
+		return fmt.Sprintf("Can't send next day %s", err)
+	}
+	if queued == 0 {
+		return fmt.Sprintf("Day %d is already on its way.", day)
+	}
+	return fmt.Sprintf("Here is day %d of your plan.", day)
+}
+
+// RepeatToday sends again verses of last delivered day.
+func (s *service) RepeatToday(senderID string) []string {
+	userData, err := s.deliveredUser(senderID)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	verses, err := getDay(s.bsvc, userData, userData.LastDeliveredDay)
+	if err != nil {
+		s.log.Log("msg", "repeat day error", "user_id", senderID, "day", userData.LastDeliveredDay, "err", err)
+		return []string{"Sorry! Something gone wrong, can't find day to repeat."}
+	}
+	return verses
+}
+
+// Pause stops deliveries for given time, days of pause are not caught up.
+func (s *service) Pause(senderID string, d time.Duration) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+
+	userData.PausedUntil = time.Now().Add(d)
+
+	err = PutUserData(userData, s.DB)
+	if err != nil {
+		return err.Error()
+	}
+
+	s.Reschedule(userData)
+	return fmt.Sprintf("Deliveries are paused until %s, use *resume* to continue earlier.",
+		userData.PausedUntil.In(userData.location()).Format("2006-01-02 15:04 MST"))
+}
+
+// Resume ends pause, missed days are not caught up.
+func (s *service) Resume(senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+	now := time.Now()
+	if !userData.paused(now) {
+		return "Your deliveries are not paused."
+	}
+
+	// Keep pause in the past, so days of pause aren't counted as missed.
+	userData.PausedUntil = now
+
+	err = PutUserData(userData, s.DB)
+	if err != nil {
+		return err.Error()
+	}
+
+	s.Reschedule(userData)
+	return fmt.Sprintf("Deliveries are resumed at %s.", userData.scheduleString())
+}
+
+// deliveredUser returns user which received at least one delivery,
+// otherwise message for user is returned as error.
+func (s *service) deliveredUser(senderID string) (*User, error) {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return nil, errors.New("Can't find your user in database, maybe you want to `start` your schedule.")
+	}
+	if userData.LastDeliveredAt.IsZero() {
+		return nil, fmt.Errorf("Nothing was delivered yet, first day is coming at %s.", userData.scheduleString())
+	}
+	return userData, nil
+}
+
+// MarkRead confirms that last delivered day was read.
+func (s *service) MarkRead(senderID string) string {
+	userData, err := s.deliveredUser(senderID)
+	if err != nil {
+		return err.Error()
+	}
+
+	now := time.Now()
+	day := userData.LastDeliveredDay
+	batch := new(leveldb.Batch)
+	confirmed, err := recordConfirmed(batch, senderID, day, now, s.DB)
+	if err != nil {
+		s.log.Log("msg", "error while reading history", "user_id", senderID, "day", day, "err", err)
+		return err.Error()
+	}
+	if !confirmed {
+		return fmt.Sprintf("Day %d was already marked as read.", day)
+	}
+
+	userData.LastReadDay = day
+	userData.LastReadAt = now
+	if userData.RequireConfirm && userData.CurrentDay == day {
+		userData.CurrentDay = day + 1
+	}
+
+	data, err := Marshal(userData)
+	if err != nil {
+		return err.Error()
+	}
+	batch.Put([]byte(senderID), data)
+	if err := s.DB.Write(batch, nil); err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("Great! Day %d is marked as read.", day)
+}
+
+// RemindLater delivers last day again after delay, plan is not advanced.
+func (s *service) RemindLater(senderID string, delay time.Duration) string {
+	userData, err := s.deliveredUser(senderID)
+	if err != nil {
+		return err.Error()
+	}
+
+	day := userData.LastDeliveredDay
+	verses, err := getDay(s.bsvc, userData, day)
+	if err != nil {
+		s.log.Log("msg", "remind later error", "user_id", senderID, "day", day, "err", err)
+		return "Sorry! Something gone wrong, can't find day to send again."
+	}
+	at := time.Now().Add(delay)
+	queued, err := s.outbox.Enqueue(senderID, nil, &OutboxEntry{
+		SenderID:     senderID,
+		Day:          day,
+		NextDay:      day + 1,
+		Parts:        verses,
+		QuickReplies: dailyQuickReplies,
+		NextAttempt:  at,
+	})
+	if err != nil {
+		s.log.Log("msg", "error while saving delivery", "user_id", senderID, "err", err)
+		return fmt.Sprintf("Can't schedule reminder %s", err)
+	}
+	if queued == 0 {
+		return fmt.Sprintf("Day %d is already waiting to be sent, I won't send it twice.", day)
+	}
+	return fmt.Sprintf("OK, I will send day %d again at %s.", day, at.In(userData.location()).Format("15:04"))
+}
+
+// SkipToday moves plan back, so last delivered day comes again
+// with next delivery instead of the following one.
+func (s *service) SkipToday(senderID string) string {
+	userData, err := s.deliveredUser(senderID)
+	if err != nil {
+		return err.Error()
+	}
+
+	userData.CurrentDay = userData.LastDeliveredDay
+
+	err = PutUserData(userData, s.DB)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("OK, day %d will be sent again with next delivery at %s.", userData.CurrentDay, userData.scheduleString())
+}
+
+// ShowReferences lists references of last delivered day.
+func (s *service) ShowReferences(senderID string) string {
+	userData, err := s.deliveredUser(senderID)
+	if err != nil {
+		return err.Error()
+	}
+
+	refs, err := s.bsvc.GetDayReferences(userData.LastDeliveredDay)
+	if err != nil {
+		s.log.Log("msg", "show references error", "user_id", senderID, "day", userData.LastDeliveredDay, "err", err)
+		return "Sorry! Something gone wrong, can't find references of your day."
+	}
+	return fmt.Sprintf("Day %d:\n- %s", userData.LastDeliveredDay, strings.Join(refs, "\n- "))
+}
+
+// TodayReferences lists references of day delivered today, or of the
+// day coming next if nothing was delivered today yet.
+func (s *service) TodayReferences(senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+
+	now := time.Now()
+	loc := userData.location()
+	day := userData.CurrentDay
+	next := fmt.Sprintf("coming at %s", userData.scheduleString())
+	if !userData.LastDeliveredAt.IsZero() && calendarDay(userData.LastDeliveredAt, loc).Equal(calendarDay(now, loc)) {
+		day = userData.LastDeliveredDay
+		next = "delivered today"
+	}
+	refs, err := s.bsvc.GetDayReferences(day)
+	if err != nil {
+		s.log.Log("msg", "today references error", "user_id", senderID, "day", day, "err", err)
+		return "Sorry! Something gone wrong, can't find references of your day."
+	}
+	return fmt.Sprintf("Day %d, %s:\n- %s", day, next, strings.Join(refs, "\n- "))
+}
@@ -0,0 +1,244 @@
+package messenger
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jozuenoon/biblia2y/bible"
+)
+
+// fakeBible serves plan with two verses every day in "bt" (default)
+// and "bw" translations, the latter lacks "rdz 1,3".
+type fakeBible struct {
+	bible.Service
+}
+
+func (fakeBible) GetDay(translation string, day int) ([]string, error) {
+	if day < 0 || day > 10 {
+		return nil, fmt.Errorf("plan day does not exists")
+	}
+	prefix := ""
+	switch translation {
+	case "", "bt":
+	case "bw":
+		prefix = "bw "
+	default:
+		return nil, bible.ErrUnknownTranslation
+	}
+	return []string{fmt.Sprintf("%sday %d verse 1", prefix, day), fmt.Sprintf("%sday %d verse 2", prefix, day)}, nil
+}
+
+func (fakeBible) GetDayReferences(day int) ([]string, error) {
+	return []string{fmt.Sprintf("Rdz %d", day), fmt.Sprintf("Mt %d", day)}, nil
+}
+
+func (fakeBible) MaxDay() int {
+	return 10
+}
+
+func (fakeBible) GetTextByReference(translation, ref string) (string, error) {
+	return "", fmt.Errorf("not a reference")
+}
+
+func (fakeBible) GetPassages(translation, ref string) ([]*bible.Passage, error) {
+	var passages []*bible.Passage
+	for _, r := range strings.Split(ref, "; ") {
+		p, err := fakePassage(translation, r)
+		if err != nil {
+			return nil, err
+		}
+		passages = append(passages, p)
+	}
+	return passages, nil
+}
+
+func fakePassage(translation, ref string) (*bible.Passage, error) {
+	if ref == "rdz 1,99" {
+		return nil, &bible.ParseError{Pos: 6, Found: "99", Err: bible.ErrVerseOutOfRange, Last: 31}
+	}
+	if ref != "rdz 1,1" && ref != "rdz 1,3" {
+		return nil, fmt.Errorf("not a reference")
+	}
+	switch translation {
+	case "", "bt":
+		translation = "bt"
+	case "bw":
+		if ref == "rdz 1,3" {
+			return nil, bible.ErrVerseNotInTranslation
+		}
+	default:
+		return nil, bible.ErrUnknownTranslation
+	}
+	return &bible.Passage{
+		Header:       ref,
+		Translation:  translation,
+		Verses:       []string{translation + " " + ref},
+		Text:         ref + "\n " + translation + " " + ref,
+		Chapter:      "rdz 1",
+		WholeChapter: true,
+	}, nil
+}
+
+func (fakeBible) Search(translation, query string, limit int) ([]bible.SearchResult, error) {
+	switch query {
+	case "":
+		return nil, bible.ErrEmptyQuery
+	case "niebo":
+		return []bible.SearchResult{
+			{Label: "001001001", Text: "Na początku Bóg stworzył niebo i ziemię.", Score: 2},
+			{Label: "099001001", Text: "Verse unknown in default translation.", Score: 1},
+		}, nil
+	}
+	return nil, nil
+}
+
+func (fakeBible) NewVerseFromSingleLabel(label bible.Label) (*bible.Verse, error) {
+	if label != "001001001" {
+		return nil, fmt.Errorf("given label does not exist")
+	}
+	return &bible.Verse{}, nil
+}
+
+func (fakeBible) VerseHeader(*bible.Verse) (string, error) {
+	return "rdz 1,1", nil
+}
+
+func (fakeBible) Translations() []string {
+	return []string{"bt", "bw"}
+}
+
+func (fakeBible) DefaultTranslation() string {
+	return "bt"
+}
+
+func newTestService(t *testing.T) *service {
+	db := newTestDB(t)
+	p := &fakePoster{}
+	s := &service{
+		DB:   db,
+		log:  log.NewNopLogger(),
+		bsvc: fakeBible{},
+		psvc: p,
+	}
+	s.sched = NewScheduler(func(string, time.Time) {}, s.log)
+	s.outbox = NewOutbox(db, p, s.log)
+	return s
+}
+
+func TestService_dailyQuickReplies(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	user := &User{SenderID: "1", CurrentDay: 4, Zone: "UTC", LastDeliveredDay: 3, LastDeliveredAt: time.Now()}
+	if err := PutUserData(user, s.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{PayloadRead, "Great! Day 3 is marked as read."},
+		{PayloadShowReferences, "Day 3:\n- Rdz 3\n- Mt 3"},
+		{PayloadSkipToday, "OK, day 3 will be sent again with next delivery at 00:00 UTC."},
+	}
+	for _, tt := range tests {
+		out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: tt.payload})
+		if len(out.Message) != 1 || out.Message[0].Text != tt.want {
+			t.Errorf("%s: reply = %+v, want %q", tt.payload, out.Message, tt.want)
+		}
+	}
+
+	got, err := GetUserData("1", s.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastReadDay != 3 || got.LastReadAt.IsZero() || got.CurrentDay != 3 {
+		t.Errorf("unexpected user %+v", got)
+	}
+
+	// Reminder waits in outbox, plan is not advanced by it.
+	start := time.Now()
+	s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: PayloadRemindLater})
+	entries, err := s.outbox.Entries("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Day != 3 || entries[0].NextAttempt.Before(start.Add(remindLaterDelay)) {
+		t.Fatalf("unexpected outbox %+v", entries)
+	}
+
+	// Reminder is already pending, user is told so.
+	out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: PayloadRemindLater})
+	if want := "Day 3 is already waiting to be sent, I won't send it twice."; len(out.Message) != 1 || out.Message[0].Text != want {
+		t.Errorf("reply = %+v, want %q", out.Message, want)
+	}
+}
+
+func TestService_payloadsBeforeFirstDelivery(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	user := &User{SenderID: "1", Zone: "UTC", ScheduleTime: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)}
+	if err := PutUserData(user, s.DB); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range []string{PayloadRead, PayloadRemindLater, PayloadSkipToday, PayloadShowReferences, PayloadRepeatToday} {
+		out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: payload})
+		if want := "Nothing was delivered yet, first day is coming at 08:00 UTC."; len(out.Message) != 1 || out.Message[0].Text != want {
+			t.Errorf("%s: reply = %+v, want %q", payload, out.Message, want)
+		}
+	}
+}
+
+func TestService_TodayReferences(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	schedule := time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		user *User
+		want string
+	}{
+		{"before first delivery", &User{SenderID: "1", Zone: "UTC", ScheduleTime: schedule}, "Day 0, coming at 08:00 UTC:\n- Rdz 0\n- Mt 0"},
+		{"delivered today", &User{SenderID: "1", Zone: "UTC", ScheduleTime: schedule, CurrentDay: 4, LastDeliveredDay: 3, LastDeliveredAt: time.Now()}, "Day 3, delivered today:\n- Rdz 3\n- Mt 3"},
+		{"delivered before", &User{SenderID: "1", Zone: "UTC", ScheduleTime: schedule, CurrentDay: 4, LastDeliveredDay: 3, LastDeliveredAt: time.Now().AddDate(0, 0, -2)}, "Day 4, coming at 08:00 UTC:\n- Rdz 4\n- Mt 4"},
+	}
+	for _, tt := range tests {
+		if err := PutUserData(tt.user, s.DB); err != nil {
+			t.Fatal(err)
+		}
+		out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: PayloadTodayReferences})
+		if len(out.Message) != 1 || out.Message[0].Text != tt.want {
+			t.Errorf("%s: reply = %+v, want %q", tt.name, out.Message, tt.want)
+		}
+	}
+}
+
+func TestService_stopConfirmation(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	if err := PutUserData(&User{SenderID: "1", Zone: "UTC"}, s.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: PayloadStop})
+	if len(out.Message) != 1 || !reflect.DeepEqual(out.Message[0].QuickReplies, stopQuickReplies) {
+		t.Fatalf("reply = %+v, want confirmation", out.Message)
+	}
+	s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: PayloadStopCancel})
+	if _, err := GetUserData("1", s.DB); err != nil {
+		t.Fatalf("user removed without confirmation: %v", err)
+	}
+
+	s.ParseMessage(&ParseMessageInput{SenderID: "1", Payload: PayloadStopConfirm})
+	if _, err := GetUserData("1", s.DB); err == nil {
+		t.Error("user kept after confirmed stop")
+	}
+}
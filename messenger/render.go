@@ -0,0 +1,158 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jozuenoon/biblia2y/bible"
+	"github.com/jozuenoon/biblia2y/poster"
+)
+
+// PayloadChapter requests passage given after colon, e.g. "CHAPTER:rodz 2".
+const PayloadChapter = "CHAPTER"
+
+// Generic template limits, longer passages are sent as text.
+const (
+	cardTitleLimit    = 80
+	cardSubtitleLimit = 80
+)
+
+// splitPayload separates payload name from its argument.
+func splitPayload(payload string) (string, string) {
+	if i := strings.Index(payload, ":"); i >= 0 {
+		return payload[:i], payload[i+1:]
+	}
+	return payload, ""
+}
+
+func chapterPayload(ref string) string {
+	return PayloadChapter + ":" + ref
+}
+
+// textMessages wraps texts as plain messages.
+func textMessages(texts ...string) []poster.Message {
+	msgs := make([]poster.Message, 0, len(texts))
+	for _, t := range texts {
+		msgs = append(msgs, poster.Message{Text: t})
+	}
+	return msgs
+}
+
+// renderPassage returns card with header as title and verse as subtitle,
+// buttons lead to full and next chapter. Passage which doesn't fit
+// the card is sent as text with buttons turned into quick replies.
+func renderPassage(p *bible.Passage) poster.Message {
+	var buttons []poster.Button
+	if !p.WholeChapter && p.Chapter != "" {
+		buttons = append(buttons, poster.Button{Title: "Read full chapter", Payload: chapterPayload(p.Chapter)})
+	}
+	if p.NextChapter != "" {
+		buttons = append(buttons, poster.Button{Title: "Next chapter", Payload: chapterPayload(p.NextChapter)})
+	}
+
+	if len(p.Verses) == 1 &&
+		utf8.RuneCountInString(p.Header) <= cardTitleLimit &&
+		utf8.RuneCountInString(p.Verses[0]) <= cardSubtitleLimit {
+		return poster.Message{Card: &poster.Card{
+			Title:    p.Header,
+			Subtitle: p.Verses[0],
+			Buttons:  buttons,
+		}}
+	}
+
+	msg := poster.Message{Text: p.Text}
+	for _, b := range buttons {
+		msg.QuickReplies = append(msg.QuickReplies, poster.QuickReply{Title: b.Title, Payload: b.Payload})
+	}
+	return msg
+}
+
+// lookupPassage renders passage of reference in translation preferred by
+// user. Verse missing in versification of that translation is taken from
+// default one with a note, error means that ref isn't a reference.
+func (s *service) lookupPassage(ref, senderID string) ([]poster.Message, error) {
+	translation := s.userTranslation(senderID)
+	passages, err := s.bsvc.GetPassages(translation, ref)
+	switch err {
+	case nil:
+		return renderPassages(passages), nil
+	case bible.ErrUnknownTranslation:
+		// Preferred translation was removed from configuration.
+		passages, err = s.bsvc.GetPassages("", ref)
+		if err != nil {
+			return nil, err
+		}
+		return renderPassages(passages), nil
+	case bible.ErrVerseNotInTranslation:
+		passages, err = s.bsvc.GetPassages("", ref)
+		if err != nil {
+			return nil, err
+		}
+		headers := make([]string, 0, len(passages))
+		for _, p := range passages {
+			headers = append(headers, p.Header)
+		}
+		note := fmt.Sprintf("%s doesn't exist in %s translation, showing %s.", strings.Join(headers, "; "), translation, passages[0].Translation)
+		return append([]poster.Message{{Text: note}}, renderPassages(passages)...), nil
+	}
+	return nil, err
+}
+
+// renderPassages renders every passage as separate message.
+func renderPassages(passages []*bible.Passage) []poster.Message {
+	msgs := make([]poster.Message, 0, len(passages))
+	for _, p := range passages {
+		msgs = append(msgs, renderPassage(p))
+	}
+	return msgs
+}
+
+// Passage renders passage of reference, user gets error text
+// when reference can't be found.
+func (s *service) Passage(ref, senderID string) []poster.Message {
+	msgs, err := s.lookupPassage(ref, senderID)
+	if err != nil {
+		s.log.Log("msg", "passage error", "ref", ref, "err", err)
+		return textMessages("Sorry! Can't find " + ref + ".")
+	}
+	return msgs
+}
+
+// referenceHint explains why message which looks like reference, i.e.
+// has digits in it, can't be found. Empty when it doesn't look like one.
+func referenceHint(err error, msg string) string {
+	pe, ok := err.(*bible.ParseError)
+	if !ok || !strings.ContainsAny(msg, "0123456789") {
+		return ""
+	}
+	switch cause := pe.Err.(type) {
+	case *bible.BookError:
+		if len(cause.Suggestions) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("I don't know book %q, did you mean: %s?", cause.Name, strings.Join(cause.Suggestions, ", "))
+	case nil:
+		// Syntax error, message without known book isn't reference.
+		if pe.Expected == "book" || pe.Expected == "reference" {
+			return ""
+		}
+		runes := []rune(msg)
+		if pe.Pos > len(runes) {
+			return ""
+		}
+		return fmt.Sprintf("I expected %s after %q.", pe.Expected, strings.TrimSpace(string(runes[:pe.Pos])))
+	}
+	switch pe.Err {
+	case bible.ErrChapterOutOfRange:
+		if pe.Last == 0 {
+			return "Sorry, this book isn't available."
+		}
+		return fmt.Sprintf("There is no chapter %s, the book has %d chapters.", pe.Found, pe.Last)
+	case bible.ErrVerseOutOfRange:
+		return fmt.Sprintf("There is no verse %s, the chapter has %d verses.", pe.Found, pe.Last)
+	case bible.ErrInvertedRange:
+		return fmt.Sprintf("Range can't end at %s, it's before its start.", pe.Found)
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package messenger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/jozuenoon/biblia2y/bible"
+	"github.com/jozuenoon/biblia2y/poster"
+)
+
+func Test_renderPassage(t *testing.T) {
+	long := strings.Repeat("ą", cardSubtitleLimit+1)
+	tests := []struct {
+		name    string
+		passage *bible.Passage
+		want    poster.Message
+	}{
+		{"card", &bible.Passage{
+			Header:      "rodz 1,1",
+			Verses:      []string{"Na początku Bóg stworzył niebo i ziemię."},
+			Text:        "rodz 1,1\n 1,1 Na początku Bóg stworzył niebo i ziemię.",
+			Chapter:     "rodz 1",
+			NextChapter: "rodz 2",
+		}, poster.Message{Card: &poster.Card{
+			Title:    "rodz 1,1",
+			Subtitle: "Na początku Bóg stworzył niebo i ziemię.",
+			Buttons: []poster.Button{
+				{Title: "Read full chapter", Payload: "CHAPTER:rodz 1"},
+				{Title: "Next chapter", Payload: "CHAPTER:rodz 2"},
+			},
+		}}},
+		{"whole last chapter", &bible.Passage{
+			Header:       "wy 1,1",
+			Verses:       []string{"Oto imiona synów Izraela."},
+			Text:         "wy 1,1\n 1,1 Oto imiona synów Izraela.",
+			Chapter:      "wy 1",
+			WholeChapter: true,
+		}, poster.Message{Card: &poster.Card{
+			Title:    "wy 1,1",
+			Subtitle: "Oto imiona synów Izraela.",
+		}}},
+		{"long verse falls back to text", &bible.Passage{
+			Header:  "rodz 1,2",
+			Verses:  []string{long},
+			Text:    "rodz 1,2\n 2 " + long,
+			Chapter: "rodz 1",
+		}, poster.Message{
+			Text:         "rodz 1,2\n 2 " + long,
+			QuickReplies: []poster.QuickReply{{Title: "Read full chapter", Payload: "CHAPTER:rodz 1"}},
+		}},
+		{"many verses fall back to text", &bible.Passage{
+			Header:       "rodz 2,1-2",
+			Verses:       []string{"a", "b"},
+			Text:         "rodz 2,1-2\n 2,1 a 2 b",
+			Chapter:      "rodz 2",
+			WholeChapter: true,
+		}, poster.Message{Text: "rodz 2,1-2\n 2,1 a 2 b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderPassage(tt.passage); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderPassage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderPassagePosted(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	p := poster.New(poster.NewFacebook("token", srv.URL+"/me/messages?access_token=%s"), log.NewNopLogger(), poster.Options{Rate: 1000})
+	msg := renderPassage(&bible.Passage{
+		Header:      "rodz 1,1",
+		Verses:      []string{"Na początku Bóg stworzył niebo i ziemię."},
+		Text:        "rodz 1,1\n 1,1 Na początku Bóg stworzył niebo i ziemię.",
+		Chapter:     "rodz 1",
+		NextChapter: "rodz 2",
+	})
+	if err := p.Send("1", []poster.Message{msg}, poster.Reply); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 1 {
+		t.Fatalf("requests = %d, want 1", len(bodies))
+	}
+	want := `{"type":"template","payload":{"template_type":"generic","elements":[{"title":"rodz 1,1","subtitle":"Na początku Bóg stworzył niebo i ziemię.","buttons":[{"type":"postback","title":"Read full chapter","payload":"CHAPTER:rodz 1"},{"type":"postback","title":"Next chapter","payload":"CHAPTER:rodz 2"}]}]}}`
+	if !strings.Contains(bodies[0], `"attachment":`+want) {
+		t.Errorf("posted %s, want attachment %s", bodies[0], want)
+	}
+}
+
+func Test_splitPayload(t *testing.T) {
+	tests := []struct {
+		payload, name, arg string
+	}{
+		{"READ", "READ", ""},
+		{"CHAPTER:rodz 2", "CHAPTER", "rodz 2"},
+		{"CHAPTER:", "CHAPTER", ""},
+	}
+	for _, tt := range tests {
+		name, arg := splitPayload(tt.payload)
+		if name != tt.name || arg != tt.arg {
+			t.Errorf("splitPayload(%q) = %q, %q, want %q, %q", tt.payload, name, arg, tt.name, tt.arg)
+		}
+	}
+}
+
+func Test_referenceHint(t *testing.T) {
+	bookErr := &bible.ParseError{Found: "rodx", Err: &bible.BookError{Name: "rodx", Suggestions: []string{"rodz"}}}
+	tests := []struct {
+		name string
+		err  error
+		msg  string
+		want string
+	}{
+		{"misspelled book", bookErr, "rodx 1,1", `I don't know book "rodx", did you mean: rodz?`},
+		{"not a reference", bookErr, "rodx", ""},
+		{"no suggestions", &bible.ParseError{Found: "xyz", Err: &bible.BookError{Name: "xyz"}}, "xyz 1", ""},
+		{"chapter out of range", &bible.ParseError{Pos: 5, Found: "51", Err: bible.ErrChapterOutOfRange, Last: 50}, "rodz 51,1",
+			"There is no chapter 51, the book has 50 chapters."},
+		{"missing book", &bible.ParseError{Pos: 4, Found: "1", Err: bible.ErrChapterOutOfRange}, "tob 1", "Sorry, this book isn't available."},
+		{"verse out of range", &bible.ParseError{Pos: 7, Found: "40", Err: bible.ErrVerseOutOfRange, Last: 31}, "rodz 1,40",
+			"There is no verse 40, the chapter has 31 verses."},
+		{"inverted range", &bible.ParseError{Pos: 9, Found: "1", Err: bible.ErrInvertedRange}, "rodz 1,3-1",
+			"Range can't end at 1, it's before its start."},
+		{"syntax", &bible.ParseError{Pos: 9, Found: "x", Expected: "verse"}, "łk 1,1-2,x", `I expected verse after "łk 1,1-2,".`},
+		{"syntax at end", &bible.ParseError{Pos: 7, Expected: "verse"}, "rodz 1,", `I expected verse after "rodz 1,".`},
+		{"no book", &bible.ParseError{Found: "12", Expected: "book"}, "12 apostles", ""},
+		{"other error", fmt.Errorf("parser error"), "rodz 1,1", ""},
+	}
+	for _, tt := range tests {
+		if got := referenceHint(tt.err, tt.msg); got != tt.want {
+			t.Errorf("%s: referenceHint() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestService_ParseMessageReferenceHint(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Message: "Rdz 1,99"})
+	want := "There is no verse 99, the chapter has 31 verses."
+	if len(out.Message) != 1 || out.Message[0].Text != want {
+		t.Errorf("reply = %+v, want %q", out.Message, want)
+	}
+}
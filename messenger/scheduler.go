@@ -0,0 +1,166 @@
+package messenger
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Scheduler keeps single timer for all subscribers. Pending deliveries
+// are kept in min-heap ordered by due time, so scheduler wakes up only
+// when next delivery is due. Add, reschedule and cancel are O(log n).
+type Scheduler struct {
+	mu      sync.Mutex
+	queue   deliveryQueue
+	entries map[string]*delivery
+	// Wakes run loop when head of the queue changed.
+	wake chan struct{}
+	// Called in separate goroutine for each due delivery.
+	task func(senderID string, due time.Time)
+	log  log.Logger
+}
+
+type delivery struct {
+	SenderID string
+	Due      time.Time
+	index    int
+}
+
+func NewScheduler(task func(senderID string, due time.Time), log log.Logger) *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*delivery),
+		wake:    make(chan struct{}, 1),
+		task:    task,
+		log:     log,
+	}
+}
+
+// Schedule adds delivery for sender or moves existing one to new due time.
+func (s *Scheduler) Schedule(senderID string, due time.Time) {
+	s.mu.Lock()
+	if d, ok := s.entries[senderID]; ok {
+		d.Due = due
+		heap.Fix(&s.queue, d.index)
+	} else {
+		d := &delivery{SenderID: senderID, Due: due}
+		heap.Push(&s.queue, d)
+		s.entries[senderID] = d
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Cancel removes pending delivery of sender if any.
+func (s *Scheduler) Cancel(senderID string) {
+	s.mu.Lock()
+	if d, ok := s.entries[senderID]; ok {
+		heap.Remove(&s.queue, d.index)
+		delete(s.entries, senderID)
+	}
+	s.mu.Unlock()
+	s.notify()
+}
+
+// Due returns time of pending delivery of sender.
+func (s *Scheduler) Due(senderID string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.entries[senderID]; ok {
+		return d.Due, true
+	}
+	return time.Time{}, false
+}
+
+// Len returns number of pending deliveries.
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queue.Len()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// popDue removes and returns all deliveries due at now, and time
+// of next delivery if any is left.
+func (s *Scheduler) popDue(now time.Time) ([]*delivery, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*delivery
+	for s.queue.Len() > 0 && !s.queue[0].Due.After(now) {
+		d := heap.Pop(&s.queue).(*delivery)
+		delete(s.entries, d.SenderID)
+		due = append(due, d)
+	}
+	if s.queue.Len() == 0 {
+		return due, time.Time{}, false
+	}
+	return due, s.queue[0].Due, true
+}
+
+// Run dispatches due deliveries until done is closed.
+func (s *Scheduler) Run(done <-chan struct{}) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		due, next, ok := s.popDue(time.Now())
+		for _, d := range due {
+			s.log.Log("msg", "delivery due", "user_id", d.SenderID, "due", d.Due)
+			go s.task(d.SenderID, d.Due)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var wait <-chan time.Time
+		if ok {
+			timer.Reset(time.Until(next))
+			wait = timer.C
+		}
+
+		select {
+		case <-done:
+			return
+		case <-s.wake:
+		case <-wait:
+		}
+	}
+}
+
+// deliveryQueue implements heap.Interface ordered by due time.
+type deliveryQueue []*delivery
+
+func (q deliveryQueue) Len() int { return len(q) }
+
+func (q deliveryQueue) Less(i, j int) bool { return q[i].Due.Before(q[j].Due) }
+
+func (q deliveryQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *deliveryQueue) Push(x interface{}) {
+	d := x.(*delivery)
+	d.index = len(*q)
+	*q = append(*q, d)
+}
+
+func (q *deliveryQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	d := old[n-1]
+	old[n-1] = nil
+	d.index = -1
+	*q = old[:n-1]
+	return d
+}
@@ -0,0 +1,84 @@
+package messenger
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestScheduler_popDue(t *testing.T) {
+	base := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	s := NewScheduler(func(string, time.Time) {}, log.NewNopLogger())
+
+	s.Schedule("c", base.Add(3*time.Minute))
+	s.Schedule("a", base.Add(1*time.Minute))
+	s.Schedule("b", base.Add(2*time.Minute))
+	s.Schedule("d", base.Add(4*time.Minute))
+
+	// Reschedule moves existing entry instead of adding new one.
+	s.Schedule("d", base.Add(30*time.Second))
+	s.Cancel("b")
+	s.Cancel("missing")
+
+	if s.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", s.Len())
+	}
+
+	due, next, ok := s.popDue(base.Add(2 * time.Minute))
+	var got []string
+	for _, d := range due {
+		got = append(got, d.SenderID)
+	}
+	if len(got) != 2 || got[0] != "d" || got[1] != "a" {
+		t.Errorf("popDue() = %v, want [d a]", got)
+	}
+	if !ok || !next.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("popDue() next = %v %v, want %v", next, ok, base.Add(3*time.Minute))
+	}
+	if _, ok := s.Due("a"); ok {
+		t.Errorf("Due() found popped delivery")
+	}
+	if d, ok := s.Due("c"); !ok || !d.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("Due() = %v %v", d, ok)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	var mu sync.Mutex
+	var fired []string
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	s := NewScheduler(func(senderID string, due time.Time) {
+		mu.Lock()
+		fired = append(fired, senderID)
+		mu.Unlock()
+		wg.Done()
+	}, log.NewNopLogger())
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.Run(done)
+
+	now := time.Now()
+	s.Schedule("late", now.Add(time.Hour))
+	s.Schedule("second", now.Add(60*time.Millisecond))
+	s.Schedule("first", now.Add(20*time.Millisecond))
+	s.Schedule("cancelled", now.Add(40*time.Millisecond))
+	s.Cancel("cancelled")
+
+	wg.Wait()
+	// Give scheduler a chance to fire cancelled delivery if it was kept.
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fired) != 2 || fired[0] != "first" || fired[1] != "second" {
+		t.Errorf("fired = %v, want [first second]", fired)
+	}
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", s.Len())
+	}
+}
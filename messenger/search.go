@@ -0,0 +1,50 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jozuenoon/biblia2y/bible"
+)
+
+// Number of verses returned by search command.
+const searchLimit = 5
+
+// Search finds verses with words of query in translation of user.
+func (s *service) Search(query string, senderID string) string {
+	query = strings.TrimSpace(query)
+	translation := s.userTranslation(senderID)
+	results, err := s.bsvc.Search(translation, query, searchLimit)
+	if err == bible.ErrUnknownTranslation {
+		results, err = s.bsvc.Search("", query, searchLimit)
+	}
+	switch {
+	case err == bible.ErrEmptyQuery:
+		return "Use *search <words>*, e.g. *search \"niebo i ziemia\"* or *search łaska or miłość*."
+	case err != nil:
+		s.log.Log("msg", "search error", "query", query, "err", err)
+		return "Sorry! Something gone wrong, can't search now."
+	case len(results) == 0:
+		return fmt.Sprintf("Nothing found for: %s", query)
+	}
+
+	lines := []string{fmt.Sprintf("*Search:* %s", query)}
+	for _, r := range results {
+		lines = append(lines, fmt.Sprintf("*%s* %s", s.labelHeader(r.Label), r.Text))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// labelHeader returns human readable reference of verse label,
+// label itself if it's not known in default translation.
+func (s *service) labelHeader(label bible.Label) string {
+	verse, err := s.bsvc.NewVerseFromSingleLabel(label)
+	if err != nil {
+		return string(label)
+	}
+	header, err := s.bsvc.VerseHeader(verse)
+	if err != nil {
+		return string(label)
+	}
+	return header
+}
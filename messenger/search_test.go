@@ -0,0 +1,31 @@
+package messenger
+
+import (
+	"testing"
+)
+
+func TestService_Search(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{" niebo ", "*Search:* niebo\n\n" +
+			"*rdz 1,1* Na początku Bóg stworzył niebo i ziemię.\n\n" +
+			"*099001001* Verse unknown in default translation."},
+		{"ogień", "Nothing found for: ogień"},
+		{"", "Use *search <words>*, e.g. *search \"niebo i ziemia\"* or *search łaska or miłość*."},
+	}
+	for _, tt := range tests {
+		if got := s.Search(tt.query, "1"); got != tt.want {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	out := s.ParseMessage(&ParseMessageInput{SenderID: "1", Message: "Search niebo"})
+	if len(out.Message) != 1 || out.Message[0].Text != tests[0].want {
+		t.Errorf("ParseMessage() = %+v, want search results", out.Message)
+	}
+}
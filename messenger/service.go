@@ -1,39 +1,41 @@
 package messenger
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
-	"github.com/jasonlvhit/gocron"
 	"github.com/jozuenoon/biblia2y/bible"
-	"github.com/jozuenoon/biblia2y/models"
 	"github.com/jozuenoon/biblia2y/poster"
 	"github.com/syndtr/goleveldb/leveldb"
 	msgpack "gopkg.in/vmihailenco/msgpack.v2"
 )
 
 type ParseMessageInput struct {
+	// Channel message was received from, empty means Facebook.
+	Channel string
+	// Channel message ID, used to drop redelivered messages.
+	MID       string
 	SenderID  string
 	TimeStamp int
 	Message   string
+	// Payload of button or quick reply, takes precedence over Message.
+	Payload string
 }
 
 type ParseMessageOutput struct {
 	SenderID string
-	Message  []string
+	Message  []poster.Message
 }
 
 type Service interface {
 	// set time 8:30 - set time of daily event
 	// set day 1 - set day of schedule
 	// show day 1 - show day 1 verses
+	// set timezone Europe/Warsaw - set timezone of daily event
 	// start - schedule sender for bible plan
 	// stop - remove sender from bible plan
 	ParseMessage(*ParseMessageInput) *ParseMessageOutput
@@ -41,17 +43,19 @@ type Service interface {
 	// Recover after down time...
 	Recover() error
 
-	// Get response sing...
-	ResponseSink() chan<- *ParseMessageOutput
+	// Enqueue queues message for asynchronous processing,
+	// reply is sent to sender when message is parsed.
+	Enqueue(*ParseMessageInput) error
 }
 
 func New(
-	dbPath,
-	pageAccessToken,
-	facebookAPI,
-	booksPath,
-	textPath,
+	dbPath string,
+	transports map[string]poster.Transport,
+	booksPath string,
+	texts []bible.TextSource,
 	planPath string,
+	sendOptions poster.Options,
+	inboxOptions InboxOptions,
 	log log.Logger,
 	done <-chan struct{},
 ) (Service, error) {
@@ -62,38 +66,55 @@ func New(
 	}
 
 	// Get bible service...
-	bsvc, err := bible.New(booksPath, textPath, planPath, log)
+	bsvc, err := bible.New(booksPath, texts, planPath, log)
 	if err != nil {
 		return nil, err
 	}
-	// Get poster service...
-	psvc := poster.New(pageAccessToken, facebookAPI, log)
+	// Get poster service for every channel...
+	psvc := make(channels, len(transports))
+	for channel, transport := range transports {
+		psvc[channel] = poster.New(transport, log, sendOptions)
+	}
+
+	s := &service{
+		DB:   db,
+		psvc: psvc,
+		bsvc: bsvc,
+		log:  log,
+	}
+	s.inbox = NewInbox(inboxOptions, s.reply, log)
+	go s.inbox.Run(done)
 
-	messages := make(chan *ParseMessageOutput, 50)
+	// Stop send workers on shutdown...
+	go func() {
+		<-done
+		psvc.Close()
+	}()
 
+	// Report queue metrics...
 	go func() {
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-done:
 				return
-			case msg := <-messages:
-				err := psvc.ProcessMessages(msg.SenderID, msg.Message, "", "RESPONSE", "REGULAR")
-				if err != nil {
-					log.Log("msg", "failed to process message", "err", err)
-				}
+			case <-ticker.C:
+				st := psvc.Stats()
+				log.Log("msg", "send queue stats", "workers", st.Workers, "queued", st.Queued,
+					"in_flight", st.InFlight, "sent", st.Sent, "failed", st.Failed)
+				in := s.inbox.Stats()
+				log.Log("msg", "inbound queue stats", "workers", in.Workers, "queued", in.Queued,
+					"in_flight", in.InFlight, "received", in.Received, "duplicates", in.Duplicates,
+					"rejected", in.Rejected, "processed", in.Processed)
 			}
 		}
 	}()
-
-	s := &service{
-		DB:              db,
-		psvc:            psvc,
-		bsvc:            bsvc,
-		log:             log,
-		Schedulers:      make(map[string]*SchedulerTask),
-		responses:       messages,
-		pageAccessToken: pageAccessToken,
-	}
+	s.sched = NewScheduler(s.deliver, log)
+	go s.sched.Run(done)
+	s.outbox = NewOutbox(db, psvc, log)
+	s.outbox.Unreachable = s.suspend
+	go s.outbox.Run(done)
 
 	if err := s.Recover(); err != nil {
 		return nil, err
@@ -103,33 +124,55 @@ func New(
 
 type service struct {
 	// Persistent database...
-	DB              *leveldb.DB
-	Schedulers      map[string]*SchedulerTask
-	log             log.Logger
-	bsvc            bible.Service
-	psvc            poster.Service
-	responses       chan *ParseMessageOutput
-	schLock         sync.RWMutex
-	pageAccessToken string
+	DB     *leveldb.DB
+	sched  *Scheduler
+	outbox *Outbox
+	log    log.Logger
+	bsvc   bible.Service
+	psvc   poster.Service
+	inbox  *Inbox
 }
 
+// How often send queue metrics are logged.
+const statsInterval = time.Minute
+
 const (
-	startCommand   = "start"
-	stopCommand    = "stop"
-	helpCommand    = "help"
-	setTimeCommand = "set time"
-	showDayCommand = "show day"
-	setDayCommand  = "set day"
-	infoCommand    = "info"
+	startCommand          = "start"
+	stopCommand           = "stop"
+	helpCommand           = "help"
+	setTimeCommand        = "set time"
+	setTimezoneCommand    = "set timezone"
+	showDayCommand        = "show day"
+	setDayCommand         = "set day"
+	setCatchUpCommand     = "set catchup"
+	infoCommand           = "info"
+	pauseCommand          = "pause"
+	resumeCommand         = "resume"
+	readCommand           = "read"
+	statsCommand          = "stats"
+	setConfirmCommand     = "set confirm"
+	setTranslationCommand = "set translation"
+	compareCommand        = "compare"
+	searchCommand         = "search"
 )
 
 var help = `*Help:*
 - *set time 8:30* - set time of daily event
+- *set timezone Europe/Warsaw* - set your timezone (or offset like UTC+2)
 - *set day 1* - set day of schedule
+- *set catchup send|skip|merge* - what to do with days missed while bot was offline
+- *set confirm on|off* - move to next day only after you confirm reading
+- *set translation bt* - choose bible translation
+- *read* - confirm reading of the last day
+- *stats* - show your reading statistics
 - *show day 1* - show day 1 verses
 - *start* - start my schedule
+- *pause* - pause deliveries for a week
+- *resume* - resume paused deliveries
 - *stop* - remove me from bible plan
 - *dz 1,1* - write this verse
+- *compare dz 1,1-3 bt bw* - show verses in two translations
+- *search łaska* - find verses with words, use "quotes" for phrase and *or* for alternatives
 - *info* - show current schedule information
 `
 
@@ -138,35 +181,74 @@ func (s *service) ParseMessage(in *ParseMessageInput) *ParseMessageOutput {
 		s.log.Log("msg", "empty message")
 		return nil
 	}
-	out := make([]string, 0)
+	out := make([]poster.Message, 0)
 	add := func(in string) {
-		out = append(out, in)
+		out = append(out, poster.Message{Text: in})
 	}
 
+	// User records are keyed by channel and sender ID.
+	in.SenderID = userID(in.Channel, in.SenderID)
+
+	// Timezone names are case sensitive, keep original message.
+	original := strings.TrimSpace(in.Message)
 	in.Message = strings.ToLower(in.Message)
 
+	if in.Payload != "" {
+		out = append(out, s.dispatchPayload(in.Payload, in.SenderID)...)
+		s.log.Log("msg", "payload", "payload", in.Payload, "senderID", in.SenderID)
+		return &ParseMessageOutput{
+			SenderID: in.SenderID,
+			Message:  out,
+		}
+	}
+
 	// Check if message parses to verse...
-	verseText, _ := s.bsvc.GetTextByReference(in.Message)
+	passage, passageErr := s.lookupPassage(in.Message, in.SenderID)
 
 	switch {
-	case verseText != "":
-		add(verseText)
+	case in.Message == "":
+		add("Sorry I can read only text messages.")
+		add(help)
+	case passageErr == nil:
+		out = append(out, passage...)
 	case in.Message == startCommand:
 		add(s.Start(in.SenderID))
 	case in.Message == stopCommand:
 		add(s.Stop(in.SenderID))
 	case in.Message == helpCommand:
 		add(help)
+	case in.Message == pauseCommand:
+		add(s.Pause(in.SenderID, pauseWeek))
+	case in.Message == resumeCommand:
+		add(s.Resume(in.SenderID))
+	case in.Message == readCommand:
+		add(s.MarkRead(in.SenderID))
+	case in.Message == statsCommand:
+		add(s.Stats(in.SenderID))
+	case strings.HasPrefix(in.Message, searchCommand):
+		add(s.Search(original[len(searchCommand):], in.SenderID))
+	case strings.HasPrefix(in.Message, compareCommand):
+		add(s.Compare(in.Message))
+	case strings.HasPrefix(in.Message, setTranslationCommand):
+		add(s.SetTranslation(in.Message, in.SenderID))
+	case strings.HasPrefix(in.Message, setConfirmCommand):
+		add(s.SetConfirm(in.Message, in.SenderID))
+	case strings.HasPrefix(in.Message, setTimezoneCommand):
+		add(s.SetTimezone(original[len(setTimezoneCommand):], in.SenderID))
 	case strings.HasPrefix(in.Message, setTimeCommand):
 		add(s.SetTime(in.Message, in.SenderID))
 	case strings.HasPrefix(in.Message, showDayCommand):
-		for _, showDayMsg := range s.ShowDay(in.Message) {
+		for _, showDayMsg := range s.ShowDay(in.Message, in.SenderID) {
 			add(showDayMsg)
 		}
 	case strings.HasPrefix(in.Message, setDayCommand):
 		add(s.SetDay(in.Message, in.SenderID))
+	case strings.HasPrefix(in.Message, setCatchUpCommand):
+		add(s.SetCatchUp(in.Message, in.SenderID))
 	case strings.HasPrefix(in.Message, infoCommand):
 		add(s.Info(in.SenderID))
+	case referenceHint(passageErr, in.Message) != "":
+		add(referenceHint(passageErr, in.Message))
 	default:
 		add("Sorry I don't understand: \n" + in.Message)
 		add(help)
@@ -179,8 +261,21 @@ func (s *service) ParseMessage(in *ParseMessageInput) *ParseMessageOutput {
 	}
 }
 
-func (s *service) ResponseSink() chan<- *ParseMessageOutput {
-	return s.responses
+func (s *service) Enqueue(in *ParseMessageInput) error {
+	return s.inbox.Push(in)
+}
+
+// reply parses queued message and sends response to sender.
+func (s *service) reply(in *ParseMessageInput) {
+	out := s.ParseMessage(in)
+	if out == nil {
+		return
+	}
+	err := s.psvc.Send(out.SenderID, out.Message, poster.Reply)
+	if err != nil {
+		s.log.Log("msg", "failed to process message", "user_id", out.SenderID,
+			"retryable", poster.IsRetryable(err), "recipient_unavailable", poster.IsRecipientUnavailable(err), "err", err)
+	}
 }
 
 func (s *service) Stop(senderID string) string {
@@ -188,12 +283,36 @@ func (s *service) Stop(senderID string) string {
 	if err != nil {
 		return fmt.Sprintf("Error while deleting user %s", err)
 	}
-	s.schLock.Lock()
-	delete(s.Schedulers, senderID)
-	s.schLock.Unlock()
+	s.sched.Cancel(senderID)
+	if err := s.outbox.Cancel(senderID); err != nil {
+		s.log.Log("msg", "error while cancelling deliveries", "user_id", senderID, "err", err)
+	}
+	if err := deleteHistory(senderID, s.DB); err != nil {
+		s.log.Log("msg", "error while deleting history", "user_id", senderID, "err", err)
+	}
 	return "Your subscription was successfully removed."
 }
 
+// suspend stops deliveries of user whom channel can't reach anymore,
+// e.g. page was blocked. Record and history are kept, so start resumes
+// the plan where it stopped.
+func (s *service) suspend(senderID string, reason error) {
+	s.log.Log("msg", "suspending unreachable user", "user_id", senderID, "reason", reason)
+	s.sched.Cancel(senderID)
+	if err := s.outbox.Cancel(senderID); err != nil {
+		s.log.Log("msg", "error while cancelling deliveries", "user_id", senderID, "err", err)
+	}
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		s.log.Log("msg", "error while getting user data", "user_id", senderID, "err", err)
+		return
+	}
+	userData.UnreachableAt = time.Now()
+	if err := PutUserData(userData, s.DB); err != nil {
+		s.log.Log("msg", "error while saving user", "user_id", senderID, "err", err)
+	}
+}
+
 // Start will persist sender and schedule tasks...
 func (s *service) Start(senderID string) string {
 	var message string
@@ -202,11 +321,21 @@ func (s *service) Start(senderID string) string {
 	if err == nil {
 		var userData User
 		err = Unmarshal(data, &userData)
+		if err == nil && !userData.UnreachableAt.IsZero() {
+			// Deliveries were suspended, user is back.
+			userData.UnreachableAt = time.Time{}
+			if err := PutUserData(&userData, s.DB); err != nil {
+				return fmt.Sprintf("Your user can't be saved %s", err.Error())
+			}
+			s.Reschedule(&userData)
+			return fmt.Sprintf("Welcome back! Bible verses are scheduled at %s again, you are at day %d.",
+				userData.scheduleString(), userData.CurrentDay)
+		}
 		if err == nil {
 			message = fmt.Sprintf(
 				`You have bible verses scheduled at %s, currently you are at day %d.
 If you want to reset your schedule unsubscribe with *stop* command first.`,
-				userData.ScheduleTime.Format("15:04"),
+				userData.scheduleString(),
 				userData.CurrentDay)
 		} else {
 			s.log.Log("msg", "error while unmarshalling", "user_id", senderID, "err", err)
@@ -215,11 +344,21 @@ If you want to reset your schedule unsubscribe with *stop* command first.`,
 		return message
 	}
 
+	// Until user sets timezone explicitly use offset reported by Facebook.
+	details := s.getUserDetails(senderID)
+	loc, zone, _ := offsetLocation(int(details.Timezone * 3600))
+
 	// Save new user for recovery...
 	userData := User{
 		SenderID:     senderID,
-		ScheduleTime: time.Now().Add(1 * time.Minute),
+		ScheduleTime: clockTime(time.Now().In(loc).Add(1 * time.Minute)),
 		CurrentDay:   0,
+		Zone:         zone,
+		StartedAt:    time.Now(),
+		Name:         details.Name,
+		FirstName:    details.FirstName,
+		LastName:     details.LastName,
+		Timezone:     details.Timezone,
 	}
 
 	err = PutUserData(&userData, s.DB)
@@ -228,61 +367,35 @@ If you want to reset your schedule unsubscribe with *stop* command first.`,
 		return fmt.Sprintf("Your user can't be saved %s", err.Error())
 	}
 
-	// Create scheduler...
-	err = s.AddScheduler(&userData)
-	if err != nil {
-		s.log.Log("msg", "error while adding scheduler", "user_id", senderID, "err", err)
-		return fmt.Sprintf("Can't create scheduler, please retry: %s", err.Error())
-	}
+	// Schedule first delivery...
+	s.Reschedule(&userData)
 
-	s.log.Log("msg", "user saved and scheduled", "user_id", senderID)
+	s.log.Log("msg", "user saved and scheduled", "user_id", senderID, "zone", zone)
 	message = fmt.Sprintf(
-		"You have bible read plan scheduled at %s, currently you are at day %d",
-		userData.ScheduleTime.Format("15:04"),
+		"You have bible read plan scheduled at %s, currently you are at day %d.\n"+
+			"If it's not your timezone use *set timezone Europe/Warsaw*.",
+		userData.scheduleString(),
 		userData.CurrentDay,
 	)
 	return message
 }
 
-// https://graph.facebook.com/v2.6/USER_ID?fields=first_name,last_name,profile_pic,locale,timezone,gender&access_token=PAGE_ACCESS_TOKEN
-func (s *service) getUserDetails(senderID string) *models.User {
-	client := &http.Client{}
-	defaultUser := &models.User{
-		Timezone: 1,
-	}
-	fbAPI := fmt.Sprintf("https://graph.facebook.com/v2.6/%s?fields=name,first_name,last_name,timezone&access_token=%s", senderID, s.pageAccessToken)
-	req, err := http.NewRequest("GET", fbAPI, nil)
-	if err != nil {
-		// Assume CET.
-		return defaultUser
-	}
-	req.Header.Add("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		s.log.Log("msg", "user details get error to FB API", "err", err)
-		// Assume CET.
-		return defaultUser
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
+// getUserDetails returns details reported by channel, if they
+// are not available CET timezone is assumed.
+func (s *service) getUserDetails(senderID string) *poster.Profile {
+	profile, err := s.psvc.Profile(senderID)
 	if err != nil {
-		s.log.Log("msg", "can't read body", "err", err)
-		return defaultUser
-	}
-
-	user := &models.User{}
-
-	if err := json.Unmarshal(body, user); err != nil {
-		s.log.Log("msg", "body unmarshall failed", "err", err)
-		return defaultUser
+		s.log.Log("msg", "can't get user details", "user_id", senderID, "err", err)
+		return &poster.Profile{Timezone: 1}
 	}
-	return user
+	return profile
 }
 
-func MakeTask(senderID string, log log.Logger, db *leveldb.DB, bsvc bible.Service, psvc poster.Service) func() {
+// MakeTask returns task which puts today's delivery into outbox, plan
+// day is advanced by outbox only after delivery is acknowledged.
+func MakeTask(senderID string, log log.Logger, db *leveldb.DB, bsvc bible.Service, outbox *Outbox) func() {
 	return func() {
-		log.Log("msg", "sending message", "user_id", senderID)
+		log.Log("msg", "scheduling delivery", "user_id", senderID)
 
 		userData, err := GetUserData(senderID, db)
 		if err != nil {
@@ -290,28 +403,66 @@ func MakeTask(senderID string, log log.Logger, db *leveldb.DB, bsvc bible.Servic
 			return
 		}
 
-		verses, err := bsvc.GetDay(userData.CurrentDay)
-		if err != nil {
-			log.Log("msg", "error while getting verses", "user_id", senderID, "err", err)
-			// Reset day to 0 and try again...
-			userData.CurrentDay = 0
-			verses, err = bsvc.GetDay(userData.CurrentDay)
+		now := time.Now()
+		missed := len(missedSlots(userData.lastSlot(), now, userData.ScheduleTime, userData.location())) - 1
+		days := userData.catchUpDays(now)
+
+		day := userData.CurrentDay
+		reset := false
+		entries := make([]*OutboxEntry, 0, days)
+		for i := 0; i < days; i++ {
+			verses, err := getDay(bsvc, userData, day)
+			if err != nil && i > 0 {
+				// End of plan, next delivery starts it over.
+				break
+			}
 			if err != nil {
-				log.Log("msg", "error while getting verses for day 0", "user_id", senderID, "err", err)
-				return
+				log.Log("msg", "error while getting verses", "user_id", senderID, "err", err)
+				// Reset day to 0 and try again, reset is saved along
+				// with entry, so outbox advances plan from there.
+				day = 0
+				verses, err = getDay(bsvc, userData, day)
+				if err != nil {
+					log.Log("msg", "error while getting verses for day 0", "user_id", senderID, "err", err)
+					return
+				}
+				reset = true
 			}
+			entries = append(entries, &OutboxEntry{
+				SenderID:     senderID,
+				Day:          day,
+				NextDay:      day + 1,
+				Parts:        verses,
+				QuickReplies: dailyQuickReplies,
+			})
+			day++
 		}
 
-		userData.CurrentDay++
-		err = PutUserData(userData, db)
-		if err != nil {
-			log.Log("msg", "error while saving user progress", "user_id", senderID, "err", err)
+		if len(entries) > 1 {
+			log.Log("msg", "catching up missed days", "user_id", senderID, "missed", missed, "days", len(entries))
+			intro := fmt.Sprintf("You have missed %d deliveries while we were offline, here are %d days of your plan.", missed, len(entries))
+			if userData.catchUpPolicy() == catchUpMerge {
+				merged := entries[0]
+				merged.Parts = append([]string{intro}, merged.Parts...)
+				for _, e := range entries[1:] {
+					merged.Parts = append(merged.Parts, e.Parts...)
+					merged.NextDay = e.NextDay
+				}
+				entries = entries[:1]
+			} else {
+				entries[0].Parts = append([]string{intro}, entries[0].Parts...)
+			}
 		}
 
-		err = psvc.ProcessMessages(userData.SenderID, verses, "NON_PROMOTIONAL_SUBSCRIPTION", "MESSAGE_TAG", "SILENT_PUSH")
-		if err != nil {
-			log.Log("msg", "error while sending verses", "user_id", senderID, "err", err)
-			return
+		update := func(u *User) {
+			// Outbox takes responsibility for the slot, so it's not counted as missed again.
+			u.LastDeliveredAt = now
+			if reset {
+				u.CurrentDay = 0
+			}
+		}
+		if _, err := outbox.Enqueue(senderID, update, entries...); err != nil {
+			log.Log("msg", "error while saving delivery", "user_id", senderID, "err", err)
 		}
 	}
 }
@@ -321,14 +472,33 @@ func (s *service) Info(senderID string) string {
 	if err != nil {
 		return "Can't find your user in database, maybe you want to `start` your schedule."
 	}
-	return fmt.Sprintf(
-		"You have bible read plan scheduled at %s, currently you are at day %d",
-		userData.ScheduleTime.Format("15:04"),
+	next, ok := s.sched.Due(senderID)
+	if !ok {
+		next = nextDelivery(time.Now(), userData.ScheduleTime, userData.location())
+	}
+	next = next.In(userData.location())
+	info := fmt.Sprintf(
+		"You have bible read plan scheduled at %s, currently you are at day %d.\n"+
+			"Next delivery: %s (server time %s).\n"+
+			"Missed days policy: %s.\n"+
+			"Translation: %s.",
+		userData.scheduleString(),
 		userData.CurrentDay,
+		next.Format("2006-01-02 15:04 MST"),
+		next.Local().Format("2006-01-02 15:04 MST"),
+		userData.catchUpPolicy(),
+		s.translationName(userData),
 	)
+	if userData.RequireConfirm {
+		info += "\nPlan moves forward when you confirm reading."
+	}
+	if userData.paused(time.Now()) {
+		info += "\nDeliveries are paused until " + userData.PausedUntil.In(userData.location()).Format("2006-01-02 15:04 MST") + "."
+	}
+	return info
 }
 
-func (s *service) ShowDay(message string) []string {
+func (s *service) ShowDay(message string, senderID string) []string {
 	shouldBeNumber := strings.TrimSpace(strings.TrimPrefix(message, showDayCommand))
 
 	day, err := strconv.Atoi(shouldBeNumber)
@@ -336,7 +506,12 @@ func (s *service) ShowDay(message string) []string {
 		return []string{err.Error()}
 	}
 
-	verses, err := s.bsvc.GetDay(day)
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		// Show day works without subscription.
+		userData = &User{}
+	}
+	verses, err := getDay(s.bsvc, userData, day)
 	if err != nil {
 		s.log.Log("msg", "show day error", "err", err)
 		return []string{"Sorry! Something gone wrong, can't find day to show."}
@@ -344,6 +519,12 @@ func (s *service) ShowDay(message string) []string {
 	return verses
 }
 
+// User records are stored directly under sender ID, other
+// records use prefixes with "/" separator (e.g. outbox).
+func isUserKey(key []byte) bool {
+	return !bytes.Contains(key, []byte("/"))
+}
+
 func GetUserData(senderID string, db *leveldb.DB) (*User, error) {
 	data, err := db.Get([]byte(senderID), nil)
 	if err != nil {
@@ -381,6 +562,9 @@ func (s *service) SetDay(message string, senderID string) string {
 	if err != nil {
 		return err.Error()
 	}
+	if day < 0 {
+		return "Plan starts at day 0, can't set negative day."
+	}
 
 	userData.CurrentDay = day
 
@@ -388,7 +572,78 @@ func (s *service) SetDay(message string, senderID string) string {
 	if err != nil {
 		return err.Error()
 	}
-	return fmt.Sprintf("New schedule is set at day: %d. At %s", userData.CurrentDay, userData.ScheduleTime.Format("15:04"))
+	return fmt.Sprintf("New schedule is set at day: %d. At %s", userData.CurrentDay, userData.scheduleString())
+}
+
+func (s *service) SetCatchUp(msg string, senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+
+	policy, err := parseCatchUpCommand(msg)
+	if err != nil {
+		return err.Error()
+	}
+
+	userData.CatchUp = policy
+
+	err = PutUserData(userData, s.DB)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("Missed days policy is set to: %s", policy)
+}
+
+func (s *service) SetConfirm(msg string, senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+
+	switch strings.Trim(strings.TrimPrefix(msg, setConfirmCommand), " ;[]{}'.,/\\|?") {
+	case "on":
+		userData.RequireConfirm = true
+	case "off":
+		userData.RequireConfirm = false
+	default:
+		return "Use *set confirm on* or *set confirm off*."
+	}
+
+	err = PutUserData(userData, s.DB)
+	if err != nil {
+		return err.Error()
+	}
+	if userData.RequireConfirm {
+		return "Plan will move to next day after you confirm reading with *read*."
+	}
+	return "Plan will move to next day with every delivery."
+}
+
+// Stats reports reading statistics of user.
+func (s *service) Stats(senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+	history, err := GetHistory(senderID, s.DB)
+	if err != nil {
+		s.log.Log("msg", "error while reading history", "user_id", senderID, "err", err)
+		return fmt.Sprintf("Can't read your history %s", err)
+	}
+
+	st := computeStats(history, s.bsvc.MaxDay()+1, userData.StartedAt, time.Now(), userData.location())
+	return fmt.Sprintf(
+		"*Stats:*\n"+
+			"- read %d of %d days (%.1f%%)\n"+
+			"- current streak: %d days\n"+
+			"- longest streak: %d days\n"+
+			"- days behind schedule: %d",
+		st.Confirmed, st.PlanDays, st.Completion,
+		st.CurrentStreak,
+		st.LongestStreak,
+		st.Behind,
+	)
 }
 
 func (s *service) SetTime(msg string, senderID string) string {
@@ -404,21 +659,35 @@ func (s *service) SetTime(msg string, senderID string) string {
 
 	userData.ScheduleTime = newTime
 
-	s.schLock.RLock()
-	currentSched := s.Schedulers[senderID]
-	currentSched.Kill()
-	s.schLock.RUnlock()
+	err = PutUserData(userData, s.DB)
+	if err != nil {
+		return err.Error()
+	}
 
-	err = s.AddScheduler(userData)
+	s.Reschedule(userData)
+	return fmt.Sprintf("New schedule is set at: %s", userData.scheduleString())
+}
+
+func (s *service) SetTimezone(zone string, senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+
+	_, name, err := parseTimezone(zone)
 	if err != nil {
 		return err.Error()
 	}
 
+	userData.Zone = name
+
 	err = PutUserData(userData, s.DB)
 	if err != nil {
 		return err.Error()
 	}
-	return fmt.Sprintf("New schedule is set at: %s", newTime.Format("15:04"))
+
+	s.Reschedule(userData)
+	return fmt.Sprintf("New schedule is set at: %s", userData.scheduleString())
 }
 
 func parseSetTimeCommand(msg string) (time.Time, error) {
@@ -431,25 +700,45 @@ func parseSetTimeCommand(msg string) (time.Time, error) {
 	return t, nil
 }
 
-func (s *service) AddScheduler(userData *User) error {
-
-	task := MakeTask(userData.SenderID, s.log, s.DB, s.bsvc, s.psvc)
+// Reschedule puts next delivery of user into central scheduler,
+// first delivery after pause if user paused deliveries.
+func (s *service) Reschedule(userData *User) {
+	from := time.Now()
+	if userData.paused(from) {
+		from = userData.PausedUntil
+	}
+	s.sched.Schedule(userData.SenderID, nextDelivery(from, userData.ScheduleTime, userData.location()))
+}
 
-	sched, err := NewSchedulerTask(userData.SenderID, s.log, userData.ScheduleTime, task)
+// deliver is called by scheduler when user delivery is due.
+func (s *service) deliver(senderID string, due time.Time) {
+	userData, err := GetUserData(senderID, s.DB)
 	if err != nil {
-		return fmt.Errorf("error while creating scheduler %s", err.Error())
+		s.log.Log("msg", "error while getting user data", "user_id", senderID, "err", err)
+		return
 	}
-	s.schLock.Lock()
-	s.Schedulers[userData.SenderID] = sched
-	s.schLock.Unlock()
-	return nil
+	if !userData.UnreachableAt.IsZero() {
+		return
+	}
+	// Schedule tomorrow before sending, so slow send can't delay it.
+	s.Reschedule(userData)
+	if userData.paused(time.Now()) {
+		return
+	}
+
+	MakeTask(senderID, s.log, s.DB, s.bsvc, s.outbox)()
 }
 
 // Recover from down time...
 func (s *service) Recover() error {
+	now := time.Now()
 	iter := s.DB.NewIterator(nil, nil)
+	defer iter.Release()
 	for iter.Next() {
 		key := iter.Key()
+		if !isUserKey(key) {
+			continue
+		}
 		val := iter.Value()
 		var userData User
 		err := Unmarshal(val, &userData)
@@ -457,12 +746,19 @@ func (s *service) Recover() error {
 			s.log.Log("msg", "failed to unmarshall", "key", key, "err", err)
 			continue
 		}
-		err = s.AddScheduler(&userData)
-		if err != nil {
-			s.log.Log("msg", "failed to add scheduler", "key", key, "err", err)
+		if !userData.UnreachableAt.IsZero() {
+			s.log.Log("msg", "skipping suspended user", "senderID", userData.SenderID, "unreachable_at", userData.UnreachableAt)
+			continue
+		}
+		if len(missedSlots(userData.lastSlot(), now, userData.ScheduleTime, userData.location())) > 0 &&
+			userData.catchUpPolicy() != catchUpSkip {
+			// Deliver missed days right away, scheduler will pick up regular time afterwards.
+			s.sched.Schedule(userData.SenderID, now)
+			s.log.Log("msg", "recovered user with missed delivery", "senderID", userData.SenderID, "last_delivered_at", userData.LastDeliveredAt)
 			continue
 		}
-		s.log.Log("msg", "revovered user", "senderID", userData.SenderID, "scheduled_at", userData.ScheduleTime)
+		s.Reschedule(&userData)
+		s.log.Log("msg", "revovered user", "senderID", userData.SenderID, "scheduled_at", userData.scheduleString())
 	}
 	return nil
 }
@@ -475,82 +771,61 @@ func Unmarshal(b []byte, v interface{}) error {
 	return msgpack.Unmarshal(b, v)
 }
 
-func NewSchedulerTask(senderID string, log log.Logger, time time.Time, task func()) (*SchedulerTask, error) {
-	s := SchedulerTask{
-		SenderID: senderID,
-		Time:     time,
-		Task:     task,
-		log:      log,
-	}
+type User struct {
+	_msgpack     struct{} `msgpack:",omitempty"`
+	SenderID     string
+	ScheduleTime time.Time
+	CurrentDay   int
 
-	err := s.Reload()
-	if err != nil {
-		return nil, err
-	}
+	// IANA zone name or UTC offset in which ScheduleTime is interpreted.
+	Zone string
+
+	// Last delivery, used to detect days missed during down time.
+	LastDeliveredAt  time.Time
+	LastDeliveredDay int
+	// Catch up policy: send, skip or merge.
+	CatchUp string
+	// Deliveries are paused until this time.
+	PausedUntil time.Time
+	// Last day confirmed as read with quick reply.
+	LastReadDay int
+	LastReadAt  time.Time
+	// Plan advances only when user confirms reading.
+	RequireConfirm bool
+	// Time of subscription, zero for users subscribed before it was kept.
+	StartedAt time.Time
+	// Deliveries are suspended since channel reported user as
+	// unreachable, start resumes them.
+	UnreachableAt time.Time
+	// Code of preferred translation, empty means default.
+	Translation string
 
-	return &s, nil
+	Name      string
+	FirstName string
+	LastName  string
+	Timezone  float64
 }
 
-type SchedulerTask struct {
-	SenderID string
-	Time     time.Time
-	Task     func()
-	CronJob  *gocron.Scheduler
-	done     chan struct{}
-	log      log.Logger
+func (u *User) paused(now time.Time) bool {
+	return u.PausedUntil.After(now)
 }
 
-func (s *SchedulerTask) Reload() error {
-	// Kill old cron...
-	s.Kill()
-	s.CronJob = nil
-
-	// Recreate done channel...
-	s.done = make(chan struct{})
-
-	// Recreate cron job...
-	s.CronJob = gocron.NewScheduler()
-	// Assume daily display...
-	timeString := s.Time.Format("15:04")
-	if s.Task != nil {
-		s.log.Log("msg", "scheduling event", "time", timeString)
-		s.CronJob.Every(1).Day().At(timeString).Do(s.Task)
-	} else {
-		return fmt.Errorf("missing task function")
+// lastSlot returns time after which deliveries count as missed,
+// days of pause are never caught up.
+func (u *User) lastSlot() time.Time {
+	if u.PausedUntil.After(u.LastDeliveredAt) {
+		return u.PausedUntil
 	}
-
-	go s.run()
-
-	return nil
+	return u.LastDeliveredAt
 }
 
-func (s *SchedulerTask) Kill() {
-	if s.done != nil {
-		close(s.done)
-	}
+func (u *User) location() *time.Location {
+	return loadZone(u.Zone)
 }
 
-func (s *SchedulerTask) run() {
-	for {
-		select {
-		case <-s.CronJob.Start():
-			s.CronJob.Clear()
-			s.log.Log("msg", "cron exited unexpectedly", "sender_id", s.SenderID)
-		case <-s.done:
-			s.CronJob.Clear()
-			return
-		}
+func (u *User) scheduleString() string {
+	if u.Zone == "" {
+		return u.ScheduleTime.Format("15:04") + " server time"
 	}
-}
-
-type User struct {
-	_msgpack     struct{} `msgpack:",omitempty"`
-	SenderID     string
-	ScheduleTime time.Time
-	CurrentDay   int
-
-	Name      string
-	FirstName string
-	LastName  string
-	Timezone  int
+	return u.ScheduleTime.Format("15:04") + " " + u.Zone
 }
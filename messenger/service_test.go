@@ -1,9 +1,14 @@
 package messenger
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jozuenoon/biblia2y/poster"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func Test_parseSetTimeCommand(t *testing.T) {
@@ -38,3 +43,139 @@ func Test_parseSetTimeCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestService_StartFractionalTimezone(t *testing.T) {
+	tests := []struct {
+		timezone float64
+		want     string
+	}{
+		{5.5, "UTC+05:30"},
+		{5.75, "UTC+05:45"},
+		{-3.5, "UTC-03:30"},
+	}
+	for _, tt := range tests {
+		s := newTestService(t)
+		s.psvc = &fakePoster{profile: &poster.Profile{Timezone: tt.timezone}}
+
+		s.Start("1")
+		got, err := GetUserData("1", s.DB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Zone != tt.want || got.Timezone != tt.timezone {
+			t.Errorf("timezone %v: Zone = %q Timezone = %v, want %q", tt.timezone, got.Zone, got.Timezone, tt.want)
+		}
+		s.DB.Close()
+	}
+}
+
+func TestService_suspend(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	user := &User{SenderID: "1", Zone: "UTC", CurrentDay: 4}
+	if err := PutUserData(user, s.DB); err != nil {
+		t.Fatal(err)
+	}
+	batch := new(leveldb.Batch)
+	if err := recordSent(batch, "1", 3, time.Now(), s.DB); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Write(batch, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.outbox.Enqueue("1", nil, &OutboxEntry{SenderID: "1", Day: 4, NextDay: 5, Parts: []string{"day 4"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.suspend("1", fmt.Errorf("blocked"))
+	got, err := GetUserData("1", s.DB)
+	if err != nil {
+		t.Fatalf("user removed: %v", err)
+	}
+	if got.UnreachableAt.IsZero() || got.CurrentDay != 4 {
+		t.Errorf("unexpected user %+v", got)
+	}
+	if history, _ := GetHistory("1", s.DB); len(history) != 1 {
+		t.Errorf("history = %+v, want kept", history)
+	}
+	if entries, _ := s.outbox.Entries("1"); len(entries) != 0 {
+		t.Errorf("outbox not cancelled: %+v", entries)
+	}
+
+	// Start resumes the plan where it stopped.
+	if reply := s.Start("1"); !strings.HasPrefix(reply, "Welcome back!") {
+		t.Errorf("Start() = %q", reply)
+	}
+	got, err = GetUserData("1", s.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.UnreachableAt.IsZero() || got.CurrentDay != 4 {
+		t.Errorf("unexpected user after start %+v", got)
+	}
+}
+
+func TestMakeTask_resetDay(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	// Day past the end of plan.
+	user := &User{SenderID: "1", CurrentDay: 11, Zone: "UTC", CatchUp: catchUpSkip}
+	if err := PutUserData(user, s.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	MakeTask("1", s.log, s.DB, s.bsvc, s.outbox)()
+	got, err := GetUserData("1", s.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 0 {
+		t.Errorf("CurrentDay = %d, want reset to 0", got.CurrentDay)
+	}
+	entries, err := s.outbox.Entries("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Day != 0 {
+		t.Fatalf("unexpected outbox state %+v", entries)
+	}
+
+	// Plan continues from the reset.
+	s.outbox.deliver("1", time.Now())
+	if got, err = GetUserData("1", s.DB); err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentDay != 1 {
+		t.Errorf("CurrentDay = %d, want 1 after delivery", got.CurrentDay)
+	}
+}
+
+func TestService_SetDay(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	if err := PutUserData(&User{SenderID: "1", CurrentDay: 4, Zone: "UTC"}, s.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		msg  string
+		want int
+	}{
+		{setDayCommand + " 7", 7},
+		{setDayCommand + " -3", 7},
+		{setDayCommand + " 0", 0},
+	}
+	for _, tt := range tests {
+		s.SetDay(tt.msg, "1")
+		got, err := GetUserData("1", s.DB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.CurrentDay != tt.want {
+			t.Errorf("SetDay(%q): CurrentDay = %d, want %d", tt.msg, got.CurrentDay, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package messenger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Offset notation accepted by `set timezone`, e.g. "utc+2", "gmt-05:30", "+0100".
+var offsetRegexp = regexp.MustCompile(`^(?:utc|gmt)?\s*([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// parseTimezone accepts IANA zone name (Europe/Warsaw) or UTC offset (UTC+2)
+// and returns location together with its canonical name which is persisted
+// on user record.
+func parseTimezone(zone string) (*time.Location, string, error) {
+	zone = strings.Trim(zone, " ;[]{}'.,\\|?")
+	if zone == "" {
+		return nil, "", fmt.Errorf("missing timezone, try `set timezone Europe/Warsaw` or `set timezone UTC+2`")
+	}
+
+	lower := strings.ToLower(zone)
+	if lower == "utc" || lower == "gmt" || lower == "z" {
+		return time.UTC, "UTC", nil
+	}
+
+	if m := offsetRegexp.FindStringSubmatch(lower); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, "", fmt.Errorf("offset out of range: %s", zone)
+		}
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return offsetLocation(offset)
+	}
+
+	// Messages are lower cased before parsing, try canonical spelling too.
+	for _, name := range []string{zone, canonicalZoneName(zone)} {
+		loc, err := time.LoadLocation(name)
+		if err == nil && name != "" && name != "Local" {
+			return loc, loc.String(), nil
+		}
+	}
+	return nil, "", fmt.Errorf("unknown timezone: %s", zone)
+}
+
+// offsetLocation returns fixed zone for given offset in seconds east of UTC.
+func offsetLocation(offset int) (*time.Location, string, error) {
+	if offset == 0 {
+		return time.UTC, "UTC", nil
+	}
+	sign := "+"
+	abs := offset
+	if offset < 0 {
+		sign = "-"
+		abs = -offset
+	}
+	name := fmt.Sprintf("UTC%s%02d:%02d", sign, abs/3600, abs%3600/60)
+	return time.FixedZone(name, offset), name, nil
+}
+
+// canonicalZoneName capitalizes each part of IANA name,
+// e.g. america/new_york -> America/New_York.
+func canonicalZoneName(zone string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range strings.ToLower(zone) {
+		if upper {
+			b.WriteString(strings.ToUpper(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+		upper = r == '/' || r == '_' || r == '-'
+	}
+	return b.String()
+}
+
+// loadZone returns location for persisted zone name. Records created
+// before timezone support have no zone and keep server local time.
+func loadZone(zone string) *time.Location {
+	if zone == "" {
+		return time.Local
+	}
+	loc, _, err := parseTimezone(zone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// nextDelivery returns first moment after now when wall clock
+// in loc shows hour and minute of at. Daylight saving transitions
+// are resolved by time.Date normalization.
+func nextDelivery(now, at time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, at.Hour(), at.Minute(), 0, 0, loc)
+	}
+	return next
+}
+
+// clockTime drops date and zone, only wall clock is stored on user record.
+func clockTime(t time.Time) time.Time {
+	return time.Date(0, 1, 1, t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
@@ -0,0 +1,96 @@
+package messenger
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseTimezone(t *testing.T) {
+	tests := []struct {
+		name     string
+		zone     string
+		wantName string
+		wantErr  bool
+	}{
+		{"iana", "Europe/Warsaw", "Europe/Warsaw", false},
+		{"iana lower case", "europe/warsaw", "Europe/Warsaw", false},
+		{"iana underscore", "america/new_york", "America/New_York", false},
+		{"utc", "utc", "UTC", false},
+		{"offset hours", "UTC+2", "UTC+02:00", false},
+		{"offset minutes", "gmt-05:30", "UTC-05:30", false},
+		{"bare offset", "+0100", "UTC+01:00", false},
+		{"zero offset", "utc+0", "UTC", false},
+		{"out of range", "utc+20", "", true},
+		{"unknown", "mars/olympus", "", true},
+		{"empty", " ", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := parseTimezone(tt.zone)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseTimezone() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.wantName {
+				t.Errorf("parseTimezone() = %v, want %v", got, tt.wantName)
+			}
+		})
+	}
+}
+
+func Test_nextDelivery(t *testing.T) {
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(0, 1, 1, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		loc  *time.Location
+		want time.Time
+	}{
+		{
+			"later today",
+			time.Date(2019, 3, 1, 6, 0, 0, 0, time.UTC),
+			warsaw,
+			time.Date(2019, 3, 1, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			"already passed today",
+			time.Date(2019, 3, 1, 7, 30, 0, 0, time.UTC),
+			warsaw,
+			time.Date(2019, 3, 2, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			"summer time starts",
+			time.Date(2019, 3, 30, 12, 0, 0, 0, time.UTC),
+			warsaw,
+			time.Date(2019, 3, 31, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			"summer time ends",
+			time.Date(2019, 10, 26, 12, 0, 0, 0, time.UTC),
+			warsaw,
+			time.Date(2019, 10, 27, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			"fixed offset crossing utc midnight",
+			time.Date(2019, 3, 1, 23, 0, 0, 0, time.UTC),
+			time.FixedZone("UTC+10:00", 10*3600),
+			time.Date(2019, 3, 2, 22, 30, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextDelivery(tt.now, at, tt.loc)
+			if !got.Equal(tt.want) {
+				t.Errorf("nextDelivery() = %v, want %v", got.UTC(), tt.want)
+			}
+			if got.In(tt.loc).Format("15:04") != "08:30" {
+				t.Errorf("nextDelivery() local time = %v, want 08:30", got.In(tt.loc))
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jozuenoon/biblia2y/bible"
+)
+
+// userTranslation returns translation preferred by user,
+// empty for unknown users means default one.
+func (s *service) userTranslation(senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return ""
+	}
+	return userData.Translation
+}
+
+// translationName returns code of translation used for user.
+func (s *service) translationName(u *User) string {
+	for _, code := range s.bsvc.Translations() {
+		if code == u.Translation {
+			return code
+		}
+	}
+	return s.bsvc.DefaultTranslation()
+}
+
+// getDay returns plan day in translation preferred by user, default
+// translation is used when preferred one is no longer configured.
+func getDay(bsvc bible.Service, u *User, day int) ([]string, error) {
+	verses, err := bsvc.GetDay(u.Translation, day)
+	if err == bible.ErrUnknownTranslation {
+		return bsvc.GetDay("", day)
+	}
+	return verses, err
+}
+
+func (s *service) SetTranslation(msg string, senderID string) string {
+	userData, err := GetUserData(senderID, s.DB)
+	if err != nil {
+		return "Can't find your user in database, maybe you want to `start` your schedule."
+	}
+
+	available := s.bsvc.Translations()
+	code := strings.Trim(strings.TrimPrefix(msg, setTranslationCommand), " ;[]{}'.,/\\|?")
+	found := false
+	for _, c := range available {
+		found = found || c == code
+	}
+	if !found {
+		return fmt.Sprintf("Unknown translation %q, use one of: %s", code, strings.Join(available, ", "))
+	}
+
+	userData.Translation = code
+	if code == s.bsvc.DefaultTranslation() {
+		userData.Translation = ""
+	}
+
+	err = PutUserData(userData, s.DB)
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("Translation is set to: %s", code)
+}
@@ -0,0 +1,120 @@
+package messenger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jozuenoon/biblia2y/poster"
+)
+
+func TestService_SetTranslation(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	if err := PutUserData(&User{SenderID: "1", Zone: "UTC", LastDeliveredAt: time.Now()}, s.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		msg  string
+		want string
+		code string
+	}{
+		{"set translation kjv", `Unknown translation "kjv", use one of: bt, bw`, ""},
+		{"set translation bw", "Translation is set to: bw", "bw"},
+		// Default translation is kept empty, so it follows configuration.
+		{"set translation bt", "Translation is set to: bt", ""},
+	}
+	for _, tt := range tests {
+		if got := s.SetTranslation(tt.msg, "1"); got != tt.want {
+			t.Errorf("SetTranslation(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+		u, err := GetUserData("1", s.DB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Translation != tt.code {
+			t.Errorf("SetTranslation(%q) translation = %q, want %q", tt.msg, u.Translation, tt.code)
+		}
+	}
+}
+
+func TestService_translationDeliveries(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	user := &User{SenderID: "1", CurrentDay: 2, Zone: "UTC", Translation: "bw"}
+	if err := PutUserData(user, s.DB); err != nil {
+		t.Fatal(err)
+	}
+	s.NextDay("1")
+	entries, err := s.outbox.Entries("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"bw day 2 verse 1", "bw day 2 verse 2"}; len(entries) != 1 || !reflect.DeepEqual(entries[0].Parts, want) {
+		t.Errorf("entries = %+v, want parts %q", entries, want)
+	}
+
+	// Translation removed from configuration falls back to default.
+	user.Translation = "kjv"
+	if err := PutUserData(user, s.DB); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.ShowDay("show day 3", "1"), []string{"day 3 verse 1", "day 3 verse 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ShowDay() = %q, want %q", got, want)
+	}
+	if got, want := s.translationName(user), "bt"; got != want {
+		t.Errorf("translationName() = %q, want %q", got, want)
+	}
+}
+
+func TestService_lookupPassage(t *testing.T) {
+	s := newTestService(t)
+	defer s.DB.Close()
+
+	if err := PutUserData(&User{SenderID: "bw", Translation: "bw"}, s.DB); err != nil {
+		t.Fatal(err)
+	}
+	if err := PutUserData(&User{SenderID: "kjv", Translation: "kjv"}, s.DB); err != nil {
+		t.Fatal(err)
+	}
+	card := func(translation, ref string) poster.Message {
+		return poster.Message{Card: &poster.Card{Title: ref, Subtitle: translation + " " + ref}}
+	}
+
+	tests := []struct {
+		name     string
+		senderID string
+		ref      string
+		want     []poster.Message
+		wantErr  bool
+	}{
+		{"unknown user", "1", "rdz 1,1", []poster.Message{card("bt", "rdz 1,1")}, false},
+		{"preferred translation", "bw", "rdz 1,1", []poster.Message{card("bw", "rdz 1,1")}, false},
+		{"missing verse", "bw", "rdz 1,3", []poster.Message{
+			{Text: "rdz 1,3 doesn't exist in bw translation, showing bt."},
+			card("bt", "rdz 1,3"),
+		}, false},
+		{"removed translation", "kjv", "rdz 1,1", []poster.Message{card("bt", "rdz 1,1")}, false},
+		{"list", "1", "rdz 1,1; rdz 1,3", []poster.Message{card("bt", "rdz 1,1"), card("bt", "rdz 1,3")}, false},
+		{"list with missing verse", "bw", "rdz 1,1; rdz 1,3", []poster.Message{
+			{Text: "rdz 1,1; rdz 1,3 doesn't exist in bw translation, showing bt."},
+			card("bt", "rdz 1,1"),
+			card("bt", "rdz 1,3"),
+		}, false},
+		{"not a reference", "bw", "hello", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.lookupPassage(tt.ref, tt.senderID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupPassage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookupPassage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,16 @@
 package messenger
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	kitlog "github.com/go-kit/kit/log"
 	kithttp "github.com/go-kit/kit/transport/http"
@@ -12,23 +19,113 @@ import (
 	m "github.com/jozuenoon/biblia2y/models"
 )
 
-func MakeHandler(bs Service, logger kitlog.Logger, verifyToken string) http.Handler {
+// MakeHandler handles Messenger webhook. Callbacks have to be signed
+// with app secret, see verifySignature.
+func MakeHandler(bs Service, logger kitlog.Logger, verifyToken, appSecret string) http.Handler {
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorLogger(logger),
 	}
 
 	r := mux.NewRouter()
 	r.Methods("POST").Path("/webhook").Handler(
-		handlers.ContentTypeHandler(kithttp.NewServer(
-			makeMessagesEndPoint(bs),
-			decodeMessage,
-			encodeResponse,
-			opts...), "application/json"))
+		verifySignature(appSecret, logger,
+			handlers.ContentTypeHandler(kithttp.NewServer(
+				makeMessagesEndPoint(bs),
+				decodeMessage,
+				encodeResponse,
+				opts...), "application/json")))
 
 	r.Methods("GET").Path("/webhook").HandlerFunc(makeVerificationEndPoint(verifyToken))
 	return r
 }
 
+const (
+	signatureHeader = "X-Hub-Signature-256"
+	signaturePrefix = "sha256="
+	// Callbacks are batched by Facebook, but never this big.
+	maxCallbackSize = 1 << 20
+)
+
+// verifySignature rejects requests which body isn't signed with app secret.
+// Facebook sends HMAC-SHA256 of raw body as hex in X-Hub-Signature-256 header.
+func verifySignature(appSecret string, logger kitlog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(signatureHeader)
+		if !strings.HasPrefix(header, signaturePrefix) {
+			logger.Log("msg", "missing callback signature", "remote_addr", r.RemoteAddr)
+			http.Error(w, "missing signature", http.StatusUnauthorized)
+			return
+		}
+		signature, err := hex.DecodeString(header[len(signaturePrefix):])
+		if err != nil {
+			logger.Log("msg", "malformed callback signature", "remote_addr", r.RemoteAddr)
+			http.Error(w, "malformed signature", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackSize))
+		if err != nil {
+			http.Error(w, "can't read body", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(appSecret))
+		mac.Write(body)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			logger.Log("msg", "invalid callback signature", "remote_addr", r.RemoteAddr)
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MakeTelegramHandler handles Telegram Bot API webhook. Requests have to carry
+// secret token registered with setWebhook, all are rejected without one.
+func MakeTelegramHandler(bs Service, logger kitlog.Logger, secretToken string) http.Handler {
+	opts := []kithttp.ServerOption{
+		kithttp.ServerErrorLogger(logger),
+	}
+
+	r := mux.NewRouter()
+	r.Methods("POST").Path("/telegram").Handler(
+		handlers.ContentTypeHandler(kithttp.NewServer(
+			makeTelegramEndPoint(bs),
+			makeTelegramDecoder(secretToken),
+			encodeTelegramResponse,
+			opts...), "application/json"))
+	return r
+}
+
+const telegramSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// statusError is reported to caller with given HTTP status.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string   { return e.msg }
+func (e *statusError) StatusCode() int { return e.status }
+
+var errTelegramSecret error = &statusError{status: http.StatusUnauthorized, msg: "invalid telegram secret token"}
+
+func makeTelegramDecoder(secretToken string) kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		got := r.Header.Get(telegramSecretHeader)
+		if secretToken == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secretToken)) != 1 {
+			return nil, errTelegramSecret
+		}
+		var update m.TelegramUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			return nil, err
+		}
+		return update, nil
+	}
+}
+
 func decodeMessage(ctx context.Context, r *http.Request) (interface{}, error) {
 	var callback m.Callback
 	err := json.NewDecoder(r.Body).Decode(&callback)
@@ -49,6 +146,16 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 	return nil
 }
 
+// encodeTelegramResponse replies with Bot API method call if endpoint
+// returned one, Telegram performs it as if it was called directly.
+func encodeTelegramResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if answer, ok := response.(*m.TelegramAnswerCallbackQuery); ok {
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(answer)
+	}
+	return encodeResponse(ctx, w, response)
+}
+
 func makeVerificationEndPoint(verifyToken string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		challenge := r.URL.Query().Get("hub.challenge")
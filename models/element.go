@@ -1,9 +1,9 @@
 package models
 
 type Element struct {
-	Title         string        `json:"title,omitempty"`
-	Subtitle      string        `json:"subtitle,omitempty"`
-	ImageURL      string        `json:"image_url,omitempty"`
-	DefaultAction DefaultAction `json:"default_action,omitempty"`
-	Buttons       []Button      `json:"buttons,omitempty"`
+	Title         string         `json:"title,omitempty"`
+	Subtitle      string         `json:"subtitle,omitempty"`
+	ImageURL      string         `json:"image_url,omitempty"`
+	DefaultAction *DefaultAction `json:"default_action,omitempty"`
+	Buttons       []Button       `json:"buttons,omitempty"`
 }
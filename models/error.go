@@ -0,0 +1,14 @@
+package models
+
+// ErrorResponse is error envelope returned by Graph API.
+type ErrorResponse struct {
+	Error GraphError `json:"error,omitempty"`
+}
+
+type GraphError struct {
+	Message      string `json:"message,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Code         int    `json:"code,omitempty"`
+	ErrorSubcode int    `json:"error_subcode,omitempty"`
+	FBTraceID    string `json:"fbtrace_id,omitempty"`
+}
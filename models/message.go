@@ -6,6 +6,7 @@ type Message struct {
 	QuickReply *struct {
 		Payload string `json:"payload,omitempty"`
 	} `json:"quick_reply,omitempty"`
-	Attachments *[]Attachment `json:"attachments,omitempty"`
-	Attachment  *Attachment   `json:"attachment,omitempty"`
+	QuickReplies []QuickReply  `json:"quick_replies,omitempty"`
+	Attachments  *[]Attachment `json:"attachments,omitempty"`
+	Attachment   *Attachment   `json:"attachment,omitempty"`
 }
@@ -1,8 +1,12 @@
 package models
 
 type Messaging struct {
-	Sender    User    `json:"sender,omitempty"`
-	Recipient User    `json:"recipient,omitempty"`
-	Timestamp int     `json:"timestamp,omitempty"`
-	Message   Message `json:"message,omitempty"`
+	Sender    User      `json:"sender,omitempty"`
+	Recipient User      `json:"recipient,omitempty"`
+	Timestamp int       `json:"timestamp,omitempty"`
+	Message   Message   `json:"message,omitempty"`
+	Postback  *Postback `json:"postback,omitempty"`
+	Referral  *Referral `json:"referral,omitempty"`
+	Read      *Read     `json:"read,omitempty"`
+	Delivery  *Delivery `json:"delivery,omitempty"`
 }
@@ -0,0 +1,37 @@
+package models
+
+// MessengerProfile holds page settings of Messenger Profile API.
+type MessengerProfile struct {
+	GetStarted     *GetStarted      `json:"get_started,omitempty"`
+	Greeting       []Greeting       `json:"greeting,omitempty"`
+	PersistentMenu []PersistentMenu `json:"persistent_menu,omitempty"`
+}
+
+type GetStarted struct {
+	Payload string `json:"payload"`
+}
+
+type Greeting struct {
+	Locale string `json:"locale"`
+	Text   string `json:"text"`
+}
+
+type PersistentMenu struct {
+	Locale                string     `json:"locale"`
+	ComposerInputDisabled bool       `json:"composer_input_disabled"`
+	CallToActions         []MenuItem `json:"call_to_actions,omitempty"`
+}
+
+// MenuItem is postback, web_url or nested item of persistent menu.
+type MenuItem struct {
+	Type          string     `json:"type"`
+	Title         string     `json:"title"`
+	Payload       string     `json:"payload,omitempty"`
+	URL           string     `json:"url,omitempty"`
+	CallToActions []MenuItem `json:"call_to_actions,omitempty"`
+}
+
+// MessengerProfileResponse is response of profile fields query.
+type MessengerProfileResponse struct {
+	Data []MessengerProfile `json:"data"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Postback is sent when user taps postback button or Get Started button.
+type Postback struct {
+	MID     string `json:"mid,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Payload string `json:"payload,omitempty"`
+	// Set when conversation was opened with m.me link or ad.
+	Referral *Referral `json:"referral,omitempty"`
+}
+
+// Referral is sent when user with existing conversation follows m.me link.
+type Referral struct {
+	Ref    string `json:"ref,omitempty"`
+	Source string `json:"source,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// Read reports that all messages sent before watermark were read.
+type Read struct {
+	Watermark int64 `json:"watermark,omitempty"`
+}
+
+// Delivery reports that messages were delivered to user device.
+type Delivery struct {
+	MIDs      []string `json:"mids,omitempty"`
+	Watermark int64    `json:"watermark,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+// TelegramUpdate is incoming update of Telegram Bot API.
+type TelegramUpdate struct {
+	UpdateID      int                    `json:"update_id"`
+	Message       *TelegramMessage       `json:"message,omitempty"`
+	CallbackQuery *TelegramCallbackQuery `json:"callback_query,omitempty"`
+}
+
+type TelegramMessage struct {
+	MessageID int           `json:"message_id"`
+	From      *TelegramUser `json:"from,omitempty"`
+	Chat      TelegramChat  `json:"chat"`
+	Date      int           `json:"date"`
+	Text      string        `json:"text,omitempty"`
+}
+
+type TelegramUser struct {
+	ID           int64  `json:"id"`
+	FirstName    string `json:"first_name,omitempty"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+type TelegramChat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// TelegramAnswerCallbackQuery is answerCallbackQuery call returned in
+// webhook response, it stops loading indicator of pressed button.
+type TelegramAnswerCallbackQuery struct {
+	Method          string `json:"method"`
+	CallbackQueryID string `json:"callback_query_id"`
+}
+
+type TelegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    TelegramUser     `json:"from"`
+	Message *TelegramMessage `json:"message,omitempty"`
+	Data    string           `json:"data,omitempty"`
+}
@@ -1,9 +1,9 @@
 package models
 
 type User struct {
-	ID        string `json:"id,omitempty"`
-	Name      string `json:"name,omitempty"`
-	FirstName string `json:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty"`
-	Timezone  int    `json:"timezone,omitempty"`
+	ID        string  `json:"id,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	FirstName string  `json:"first_name,omitempty"`
+	LastName  string  `json:"last_name,omitempty"`
+	Timezone  float64 `json:"timezone,omitempty"`
 }
@@ -0,0 +1,357 @@
+package poster
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	defaultRate      = 10
+	defaultBurst     = 10
+	defaultWorkers   = 4
+	defaultQueueSize = 100
+)
+
+// Options of dispatcher, zero values are replaced with defaults.
+type Options struct {
+	// Messages per second sent to Send API by all workers together.
+	Rate float64
+	// Number of messages which could be sent at once after idle period.
+	Burst int
+	// Number of workers posting messages.
+	Workers int
+	// Number of pending calls queued for every worker.
+	QueueSize int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Rate <= 0 {
+		o.Rate = defaultRate
+	}
+	if o.Burst <= 0 {
+		o.Burst = defaultBurst
+	}
+	if o.Workers <= 0 {
+		o.Workers = defaultWorkers
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultQueueSize
+	}
+	return o
+}
+
+// Stats is snapshot of dispatcher metrics.
+type Stats struct {
+	Workers int
+	// Calls waiting in worker queues.
+	Queued int64
+	// Calls being processed by workers.
+	InFlight int64
+	// Messages acknowledged by Send API.
+	Sent int64
+	// Messages which failed after all retries.
+	Failed int64
+}
+
+// errClosed is reported for messages which weren't sent because
+// dispatcher was closed, they could be sent again after restart.
+var errClosed = &SendError{Err: errors.New("dispatcher is closed"), Class: Retryable}
+
+type job struct {
+	recipient string
+	messages  []Message
+	kind      Kind
+	result    chan error
+
+	// Progress kept while job waits for retry.
+	started bool
+	next    int
+	attempt int
+	errs    Errors
+}
+
+// dispatcher posts messages with bounded pool of workers. All messages
+// of single recipient are handled by the same worker, so parts of one
+// call never interleave with other calls to that recipient and keep order.
+// Failed message is retried by requeuing its job with delay, worker serves
+// other recipients in the meantime.
+type dispatcher struct {
+	queues  []chan *job
+	limiter *tokenBucket
+	post    func(recipient string, msg *Message, kind Kind) *SendError
+	logger  log.Logger
+	// Backoff before second attempt, doubled with every next one.
+	backoff time.Duration
+
+	// Guards queues against sends after close.
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+	// Jobs waiting for retry, nil once closed.
+	retryMu  sync.Mutex
+	retrying map[*job]*time.Timer
+
+	queued   int64
+	inFlight int64
+	sent     int64
+	failed   int64
+}
+
+func newDispatcher(opts Options, logger log.Logger, post func(string, *Message, Kind) *SendError) *dispatcher {
+	opts = opts.withDefaults()
+	d := &dispatcher{
+		queues:   make([]chan *job, opts.Workers),
+		limiter:  newTokenBucket(opts.Rate, opts.Burst),
+		post:     post,
+		logger:   logger,
+		backoff:  retryBackoff,
+		done:     make(chan struct{}),
+		retrying: make(map[*job]*time.Timer),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan *job, opts.QueueSize)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+// dispatch queues messages of single recipient and waits for result,
+// blocks when worker queue is full.
+func (d *dispatcher) dispatch(recipient string, messages []Message, kind Kind) error {
+	j := &job{recipient: recipient, messages: messages, kind: kind, result: make(chan error, 1)}
+	atomic.AddInt64(&d.queued, 1)
+	if !d.enqueue(j) {
+		atomic.AddInt64(&d.queued, -1)
+		return Errors{errClosed}
+	}
+	return <-j.result
+}
+
+// enqueue puts job to queue of its worker, false once dispatcher is closed.
+func (d *dispatcher) enqueue(j *job) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return false
+	}
+	d.queues[d.shard(j.recipient)] <- j
+	return true
+}
+
+// close stops workers. Message being posted is finished, the rest
+// of queued and retried messages fail with errClosed.
+func (d *dispatcher) close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.done)
+	d.mu.Unlock()
+
+	d.retryMu.Lock()
+	defer d.retryMu.Unlock()
+	for j, timer := range d.retrying {
+		// Job is requeued by timer which already fired.
+		if timer.Stop() {
+			j.errs = append(j.errs, errClosed)
+			d.finish(j)
+		}
+	}
+	d.retrying = nil
+}
+
+func (d *dispatcher) shard(recipient string) int {
+	h := fnv.New32a()
+	h.Write([]byte(recipient))
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
+
+func (d *dispatcher) work(queue <-chan *job) {
+	// Jobs held back while earlier job of the same recipient
+	// waits for retry, so calls to recipient keep order.
+	held := make(map[string][]*job)
+	for {
+		select {
+		case <-d.done:
+			d.drain(queue, held)
+			return
+		case j := <-queue:
+			if !j.started {
+				d.start(j)
+				if _, ok := held[j.recipient]; ok {
+					held[j.recipient] = append(held[j.recipient], j)
+					continue
+				}
+			}
+			for j != nil && d.process(j) {
+				d.finish(j)
+				j = nextHeld(held, j.recipient)
+			}
+			if j != nil {
+				if _, ok := held[j.recipient]; !ok {
+					held[j.recipient] = nil
+				}
+			}
+		}
+	}
+}
+
+// nextHeld returns job held behind finished job of recipient.
+func nextHeld(held map[string][]*job, recipient string) *job {
+	waiting := held[recipient]
+	if len(waiting) == 0 {
+		delete(held, recipient)
+		return nil
+	}
+	held[recipient] = waiting[1:]
+	return waiting[0]
+}
+
+// drain fails queued and held jobs once dispatcher is closed.
+func (d *dispatcher) drain(queue <-chan *job, held map[string][]*job) {
+	for _, jobs := range held {
+		for _, j := range jobs {
+			j.errs = append(j.errs, errClosed)
+			d.finish(j)
+		}
+	}
+	for {
+		select {
+		case j := <-queue:
+			d.start(j)
+			j.errs = append(j.errs, errClosed)
+			d.finish(j)
+		default:
+			return
+		}
+	}
+}
+
+func (d *dispatcher) start(j *job) {
+	if j.started {
+		return
+	}
+	j.started = true
+	atomic.AddInt64(&d.queued, -1)
+	atomic.AddInt64(&d.inFlight, 1)
+}
+
+func (d *dispatcher) finish(j *job) {
+	atomic.AddInt64(&d.inFlight, -1)
+	if len(j.errs) > 0 {
+		j.result <- j.errs
+		return
+	}
+	j.result <- nil
+}
+
+// process posts messages of job from the first unsent one. It returns
+// false when failed message is going to be retried later.
+func (d *dispatcher) process(j *job) bool {
+	for ; j.next < len(j.messages); j.next++ {
+		select {
+		case <-d.done:
+			j.errs = append(j.errs, errClosed)
+			return true
+		default:
+		}
+		d.limiter.Wait()
+		err := d.post(j.recipient, &j.messages[j.next], j.kind)
+		if err == nil {
+			atomic.AddInt64(&d.sent, 1)
+			j.attempt = 0
+			continue
+		}
+		j.attempt++
+		d.logger.Log("msg", "send error", "recipient", j.recipient,
+			"attempt", j.attempt, "status", err.StatusCode, "code", err.Code, "subcode", err.Subcode,
+			"fbtrace_id", err.FBTraceID, "retryable", err.Temporary(), "err", err)
+		if err.Temporary() && j.attempt < maxAttempts {
+			d.retry(j, d.backoff<<uint(j.attempt-1))
+			return false
+		}
+		atomic.AddInt64(&d.failed, 1)
+		j.errs = append(j.errs, err)
+		j.attempt = 0
+		if err.Class == RecipientUnavailable {
+			// Rest of messages will fail the same way.
+			break
+		}
+	}
+	return true
+}
+
+// retry requeues job after delay.
+func (d *dispatcher) retry(j *job, delay time.Duration) {
+	d.retryMu.Lock()
+	defer d.retryMu.Unlock()
+	if d.retrying == nil {
+		j.errs = append(j.errs, errClosed)
+		d.finish(j)
+		return
+	}
+	d.retrying[j] = time.AfterFunc(delay, func() {
+		d.retryMu.Lock()
+		delete(d.retrying, j)
+		d.retryMu.Unlock()
+		if !d.enqueue(j) {
+			j.errs = append(j.errs, errClosed)
+			d.finish(j)
+		}
+	})
+}
+
+func (d *dispatcher) stats() Stats {
+	return Stats{
+		Workers:  len(d.queues),
+		Queued:   atomic.LoadInt64(&d.queued),
+		InFlight: atomic.LoadInt64(&d.inFlight),
+		Sent:     atomic.LoadInt64(&d.sent),
+		Failed:   atomic.LoadInt64(&d.failed),
+	}
+}
+
+// tokenBucket limits rate of messages shared by all workers.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// reserve takes token and returns time caller has to wait for it.
+// Tokens could go below zero, so waiting callers are served in order.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) Wait() {
+	if d := b.reserve(time.Now()); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,169 @@
+package poster
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func Test_tokenBucket_reserve(t *testing.T) {
+	start := time.Date(2019, 3, 1, 8, 0, 0, 0, time.UTC)
+	b := newTokenBucket(2, 2)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{"burst 1", start, 0},
+		{"burst 2", start, 0},
+		{"empty bucket", start, 500 * time.Millisecond},
+		{"queued behind previous", start, time.Second},
+		{"refilled", start.Add(1500 * time.Millisecond), 0},
+		{"refill is capped by burst", start.Add(time.Hour), 0},
+		{"second token of burst", start.Add(time.Hour), 0},
+		{"waits again", start.Add(time.Hour), 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := b.reserve(tt.now); got != tt.want {
+			t.Errorf("%s: reserve() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_dispatcher_order(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[string][]string)
+
+	d := newDispatcher(Options{Rate: 10000, Burst: 100, Workers: 3, QueueSize: 1}, log.NewNopLogger(), func(recipient string, msg *Message, kind Kind) *SendError {
+		mu.Lock()
+		got[recipient] = append(got[recipient], msg.Text)
+		mu.Unlock()
+		return nil
+	})
+
+	recipients := []string{"1", "2", "3", "4", "5"}
+	calls := 20
+
+	wg := sync.WaitGroup{}
+	for _, recipient := range recipients {
+		wg.Add(1)
+		go func(recipient string) {
+			defer wg.Done()
+			for c := 0; c < calls; c++ {
+				messages := []Message{
+					{Text: fmt.Sprintf("%d.a", c)},
+					{Text: fmt.Sprintf("%d.b", c)},
+				}
+				if err := d.dispatch(recipient, messages, Reply); err != nil {
+					t.Error(err)
+				}
+			}
+		}(recipient)
+	}
+	wg.Wait()
+
+	for _, recipient := range recipients {
+		msgs := got[recipient]
+		if len(msgs) != 2*calls {
+			t.Fatalf("recipient %s got %d messages, want %d", recipient, len(msgs), 2*calls)
+		}
+		for c := 0; c < calls; c++ {
+			if msgs[2*c] != fmt.Sprintf("%d.a", c) || msgs[2*c+1] != fmt.Sprintf("%d.b", c) {
+				t.Fatalf("recipient %s messages out of order: %v", recipient, msgs)
+			}
+		}
+	}
+
+	st := d.stats()
+	if st.Sent != int64(2*calls*len(recipients)) || st.Queued != 0 || st.InFlight != 0 || st.Workers != 3 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func Test_dispatcher_stopsOnUnavailableRecipient(t *testing.T) {
+	var posted int
+	d := newDispatcher(Options{Rate: 10000}, log.NewNopLogger(), func(recipient string, msg *Message, kind Kind) *SendError {
+		posted++
+		return &SendError{Code: 551, Class: RecipientUnavailable}
+	})
+
+	err := d.dispatch("1", []Message{{}, {}, {}}, Update)
+	if !IsRecipientUnavailable(err) {
+		t.Errorf("dispatch() error = %v", err)
+	}
+	if posted != 1 {
+		t.Errorf("posted %d messages, want 1", posted)
+	}
+	if st := d.stats(); st.Failed != 1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func Test_dispatcher_retryDoesntBlockWorker(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		posted []string
+	)
+	d := newDispatcher(Options{Rate: 10000, Workers: 1}, log.NewNopLogger(), func(recipient string, msg *Message, kind Kind) *SendError {
+		mu.Lock()
+		defer mu.Unlock()
+		posted = append(posted, recipient+":"+msg.Text)
+		if recipient == "1" {
+			return &SendError{StatusCode: 500, Class: Retryable}
+		}
+		return nil
+	})
+	d.backoff = time.Hour
+
+	failing := make(chan error, 1)
+	go func() { failing <- d.dispatch("1", []Message{{Text: "a"}}, Update) }()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		mu.Lock()
+		n := len(posted)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first message not posted")
+		}
+	}
+
+	// Next call to the same recipient waits behind retried one,
+	// other recipients on that worker are served.
+	held := make(chan error, 1)
+	go func() { held <- d.dispatch("1", []Message{{Text: "b"}}, Update) }()
+	other := make(chan error, 1)
+	go func() { other <- d.dispatch("2", []Message{{Text: "c"}}, Update) }()
+	select {
+	case err := <-other:
+		if err != nil {
+			t.Errorf("dispatch() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("worker blocked by retry backoff")
+	}
+
+	d.close()
+	for _, result := range []chan error{failing, held} {
+		if err := <-result; !IsRetryable(err) {
+			t.Errorf("dispatch() after close error = %v, want retryable", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"1:a", "2:c"}; !reflect.DeepEqual(posted, want) {
+		t.Errorf("posted %v, want %v", posted, want)
+	}
+	if err := d.dispatch("2", []Message{{Text: "d"}}, Update); !IsRetryable(err) {
+		t.Errorf("dispatch() of closed dispatcher error = %v, want retryable", err)
+	}
+	if st := d.stats(); st.Queued != 0 || st.InFlight != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,112 @@
+package poster
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error classes of failed send.
+const (
+	// Temporary failure, message could be sent again (rate limit, 5xx, timeout).
+	Retryable = iota
+	// Request is wrong and resending won't help.
+	Permanent
+	// Conversation with recipient is closed (blocked page,
+	// deleted or unknown recipient).
+	RecipientUnavailable
+)
+
+// SendError describes single failed transport call.
+type SendError struct {
+	StatusCode int
+	Message    string
+	Type       string
+	// Channel specific error codes.
+	Code    int
+	Subcode int
+	// Facebook trace ID, useful for bug reports.
+	FBTraceID string
+	Class     int
+	// Transport error if request didn't reach channel API.
+	Err error
+}
+
+func (e *SendError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("send failed: %s", e.Err)
+	}
+	if e.FBTraceID == "" {
+		return fmt.Sprintf("send failed with status %d: %s (code %d)", e.StatusCode, e.Message, e.Code)
+	}
+	return fmt.Sprintf("send failed with status %d: %s (code %d, subcode %d, fbtrace_id %s)",
+		e.StatusCode, e.Message, e.Code, e.Subcode, e.FBTraceID)
+}
+
+// Temporary reports whether sending again could succeed.
+func (e *SendError) Temporary() bool {
+	return e.Class == Retryable
+}
+
+// newTransportError wraps error of request which didn't get any response,
+// timeouts and connection errors are worth retry.
+func newTransportError(err error) *SendError {
+	return &SendError{Err: err, Class: Retryable}
+}
+
+// classifyStatus classifies errors by HTTP status only.
+func classifyStatus(statusCode int) int {
+	if statusCode >= 500 || statusCode == 429 {
+		return Retryable
+	}
+	return Permanent
+}
+
+// Errors collects errors of messages which failed to send.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d messages failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// errorClasses returns class of every SendError in err.
+func errorClasses(err error) []int {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *SendError:
+		return []int{e.Class}
+	case Errors:
+		var classes []int
+		for _, err := range e {
+			classes = append(classes, errorClasses(err)...)
+		}
+		return classes
+	}
+	return []int{Permanent}
+}
+
+// IsRetryable reports whether all failures of err are temporary.
+func IsRetryable(err error) bool {
+	classes := errorClasses(err)
+	for _, c := range classes {
+		if c != Retryable {
+			return false
+		}
+	}
+	return len(classes) > 0
+}
+
+// IsRecipientUnavailable reports whether page can't message recipient anymore,
+// e.g. user blocked page or conversation is closed.
+func IsRecipientUnavailable(err error) bool {
+	for _, c := range errorClasses(err) {
+		if c == RecipientUnavailable {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package poster
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	retryable := &SendError{Class: Retryable}
+	permanent := &SendError{Class: Permanent}
+	unavailable := &SendError{Class: RecipientUnavailable}
+
+	tests := []struct {
+		name            string
+		err             error
+		wantRetryable   bool
+		wantUnavailable bool
+	}{
+		{"nil", nil, false, false},
+		{"single retryable", retryable, true, false},
+		{"all retryable", Errors{retryable, retryable}, true, false},
+		{"mixed", Errors{retryable, permanent}, false, false},
+		{"unavailable", Errors{retryable, unavailable}, false, true},
+		{"unknown error", fmt.Errorf("boom"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.wantRetryable)
+			}
+			if got := IsRecipientUnavailable(tt.err); got != tt.wantUnavailable {
+				t.Errorf("IsRecipientUnavailable() = %v, want %v", got, tt.wantUnavailable)
+			}
+		})
+	}
+}
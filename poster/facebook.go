@@ -0,0 +1,223 @@
+package poster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	m "github.com/jozuenoon/biblia2y/models"
+)
+
+const (
+	// Facebook accepts up to 2000 characters, keep margin for multibyte runes.
+	facebookTextLength = 1000
+
+	facebookProfileAPI = "https://graph.facebook.com/v2.6/%s?fields=name,first_name,last_name,timezone&access_token=%s"
+)
+
+// Graph API codes which mean that request should be retried later.
+var retryableCodes = map[int]bool{
+	1:   true, // API unknown
+	2:   true, // API service
+	4:   true, // API too many calls
+	17:  true, // API user too many calls
+	32:  true, // page request limit reached
+	613: true, // calls to this API have exceeded the rate limit
+}
+
+// Graph API code and subcode pairs which mean that page can't message
+// recipient anymore. Subcode 0 matches every subcode. Messages sent
+// outside of allowed window or against policy (code 10) are permanent
+// errors of single message, recipient could still be reached later.
+var unavailableCodes = map[[2]int]bool{
+	{551, 0}:       true, // this person isn't available right now
+	{100, 2018001}: true, // no matching user found
+	{200, 1545041}: true, // user blocked the page
+	{200, 2018108}: true, // user isn't receiving messages from the page
+}
+
+// Facebook sends messages with Messenger Send API.
+type Facebook struct {
+	PageAccessToken string
+	// Send API URL with %s placeholder for access token.
+	FaceBookAPI string
+	client      *http.Client
+	// Profile API URL with placeholders for user ID and access token.
+	profileAPI string
+}
+
+var _ Transport = (*Facebook)(nil)
+var _ ProfileFetcher = (*Facebook)(nil)
+
+func NewFacebook(pageAccessToken, faceBookAPI string) *Facebook {
+	return &Facebook{
+		PageAccessToken: pageAccessToken,
+		FaceBookAPI:     faceBookAPI,
+		client:          &http.Client{Timeout: requestTimeout},
+		profileAPI:      facebookProfileAPI,
+	}
+}
+
+func (f *Facebook) MaxTextLength() int {
+	return facebookTextLength
+}
+
+func (f *Facebook) SendText(recipient, text string, kind Kind) *SendError {
+	return f.post(f.response(recipient, m.Message{Text: text}, kind))
+}
+
+func (f *Facebook) SendQuickReplies(recipient, text string, replies []QuickReply, kind Kind) *SendError {
+	msg := m.Message{Text: text}
+	for _, r := range replies {
+		msg.QuickReplies = append(msg.QuickReplies, m.QuickReply{
+			ContentType: "text",
+			Title:       r.Title,
+			Payload:     r.Payload,
+		})
+	}
+	return f.post(f.response(recipient, msg, kind))
+}
+
+func (f *Facebook) SendCard(recipient string, card *Card, kind Kind) *SendError {
+	element := m.Element{
+		Title:    card.Title,
+		Subtitle: card.Subtitle,
+		ImageURL: card.ImageURL,
+	}
+	if card.URL != "" {
+		element.DefaultAction = &m.DefaultAction{Type: "web_url", URL: card.URL}
+	}
+	for _, b := range card.Buttons {
+		button := m.Button{Title: b.Title}
+		if b.URL != "" {
+			button.Type = "web_url"
+			button.URL = b.URL
+		} else {
+			button.Type = "postback"
+			button.Payload = b.Payload
+		}
+		element.Buttons = append(element.Buttons, button)
+	}
+	msg := m.Message{
+		Attachment: &m.Attachment{
+			Type: "template",
+			Payload: m.Payload{
+				TemplateType: "generic",
+				Elements:     []m.Element{element},
+			},
+		},
+	}
+	return f.post(f.response(recipient, msg, kind))
+}
+
+func (f *Facebook) response(recipient string, msg m.Message, kind Kind) *m.Response {
+	response := &m.Response{
+		Recipient: m.User{
+			ID: recipient,
+		},
+		Message: msg,
+	}
+	switch kind {
+	case Update:
+		response.Tag = "NON_PROMOTIONAL_SUBSCRIPTION"
+		response.MessagingType = "MESSAGE_TAG"
+		response.NotificationType = "SILENT_PUSH"
+	default:
+		response.MessagingType = "RESPONSE"
+		response.NotificationType = "REGULAR"
+	}
+	return response
+}
+
+func (f *Facebook) post(response *m.Response) *SendError {
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(response); err != nil {
+		return &SendError{Err: err, Class: Permanent}
+	}
+
+	url := fmt.Sprintf(f.FaceBookAPI, f.PageAccessToken)
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return &SendError{Err: err, Class: Permanent}
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return newTransportError(err)
+	}
+	defer resp.Body.Close()
+
+	respInfo, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return newTransportError(err)
+	}
+	if resp.StatusCode != 200 {
+		return newFacebookError(resp.StatusCode, respInfo)
+	}
+	return nil
+}
+
+// Profile fetches user details with Graph API.
+func (f *Facebook) Profile(recipient string) (*Profile, error) {
+	url := fmt.Sprintf(f.profileAPI, recipient, f.PageAccessToken)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, newFacebookError(resp.StatusCode, body)
+	}
+
+	user := &m.User{}
+	if err := json.Unmarshal(body, user); err != nil {
+		return nil, err
+	}
+	return &Profile{
+		Name:      user.Name,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Timezone:  user.Timezone,
+	}, nil
+}
+
+// newFacebookError parses Graph API error envelope of failed response.
+func newFacebookError(statusCode int, body []byte) *SendError {
+	e := &SendError{StatusCode: statusCode}
+	var resp m.ErrorResponse
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error.Message != "" {
+		e.Message = resp.Error.Message
+		e.Type = resp.Error.Type
+		e.Code = resp.Error.Code
+		e.Subcode = resp.Error.ErrorSubcode
+		e.FBTraceID = resp.Error.FBTraceID
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	e.Class = classifyFacebook(e)
+	return e
+}
+
+func classifyFacebook(e *SendError) int {
+	switch {
+	case unavailableCodes[[2]int{e.Code, e.Subcode}] || unavailableCodes[[2]int{e.Code, 0}]:
+		return RecipientUnavailable
+	case retryableCodes[e.Code]:
+		return Retryable
+	}
+	return classifyStatus(e.StatusCode)
+}
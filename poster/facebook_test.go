@@ -0,0 +1,302 @@
+package poster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	m "github.com/jozuenoon/biblia2y/models"
+)
+
+func Test_newFacebookError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantClass   int
+		wantCode    int
+		wantSubcode int
+		wantTrace   string
+	}{
+		{
+			"rate limit",
+			400,
+			`{"error":{"message":"(#613) Calls to this api have exceeded the rate limit.","type":"OAuthException","code":613,"fbtrace_id":"AbC"}}`,
+			Retryable, 613, 0, "AbC",
+		},
+		{
+			"blocked page",
+			400,
+			`{"error":{"message":"(#551) This person isn't available right now.","type":"OAuthException","code":551,"error_subcode":1545041,"fbtrace_id":"Dx1"}}`,
+			RecipientUnavailable, 551, 1545041, "Dx1",
+		},
+		{
+			"outside of window",
+			400,
+			`{"error":{"message":"(#10) This message is sent outside of allowed window.","type":"OAuthException","code":10,"error_subcode":2018278,"fbtrace_id":"E2"}}`,
+			Permanent, 10, 2018278, "E2",
+		},
+		{
+			"outside of window with tag",
+			400,
+			`{"error":{"message":"(#10) This message is sent outside of allowed window.","type":"OAuthException","code":10,"error_subcode":2018065,"fbtrace_id":"E3"}}`,
+			Permanent, 10, 2018065, "E3",
+		},
+		{
+			"invalid recipient",
+			400,
+			`{"error":{"message":"(#100) No matching user found","type":"OAuthException","code":100,"error_subcode":2018001,"fbtrace_id":"F3"}}`,
+			RecipientUnavailable, 100, 2018001, "F3",
+		},
+		{
+			"invalid token",
+			400,
+			`{"error":{"message":"Invalid OAuth access token.","type":"OAuthException","code":190,"fbtrace_id":"G4"}}`,
+			Permanent, 190, 0, "G4",
+		},
+		{
+			"server error without envelope",
+			502,
+			`Bad Gateway`,
+			Retryable, 0, 0, "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newFacebookError(tt.status, []byte(tt.body))
+			if got.Class != tt.wantClass || got.Code != tt.wantCode || got.Subcode != tt.wantSubcode || got.FBTraceID != tt.wantTrace {
+				t.Errorf("newFacebookError() = %+v", got)
+			}
+		})
+	}
+}
+
+// newFacebookStub returns Facebook transport talking to local Send API stand-in,
+// status codes are returned in order and requests are recorded.
+func newFacebookStub(t *testing.T, statuses []int) (*Facebook, *[]m.Response, func()) {
+	f, requests, _, closeStub := newFacebookRawStub(t, statuses)
+	return f, requests, closeStub
+}
+
+// newFacebookRawStub is like newFacebookStub, it also records request
+// bodies as sent, so fields lost by decoding can be checked.
+func newFacebookRawStub(t *testing.T, statuses []int) (*Facebook, *[]m.Response, *[]string, func()) {
+	var (
+		requests []m.Response
+		bodies   []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != "token" {
+			t.Errorf("missing access token: %s", r.URL)
+		}
+		if r.URL.Path == "/profile/1" {
+			w.Write([]byte(`{"name":"Jan Kowalski","first_name":"Jan","last_name":"Kowalski","timezone":2,"id":"1"}`))
+			return
+		}
+		if r.URL.Path == "/profile/2" {
+			w.Write([]byte(`{"name":"Ravi Kumar","first_name":"Ravi","last_name":"Kumar","timezone":5.5,"id":"2"}`))
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, string(body))
+		var response m.Response
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Error(err)
+		}
+		status := 200
+		if len(requests) < len(statuses) {
+			status = statuses[len(requests)]
+		}
+		requests = append(requests, response)
+		switch status {
+		case 551:
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":{"message":"(#551) This person isn't available right now.","code":551,"error_subcode":1545041,"fbtrace_id":"x"}}`))
+		case 429:
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":{"message":"(#613) Calls to this api have exceeded the rate limit.","code":613,"fbtrace_id":"y"}}`))
+		default:
+			w.WriteHeader(status)
+			w.Write([]byte(`{}`))
+		}
+	}))
+
+	f := NewFacebook("token", srv.URL+"/me/messages?access_token=%s")
+	f.client = srv.Client()
+	f.profileAPI = srv.URL + "/profile/%s?access_token=%s"
+	return f, &requests, &bodies, srv.Close
+}
+
+func Test_service_ProcessMessages(t *testing.T) {
+	tests := []struct {
+		name            string
+		responses       []int
+		wantCalls       int
+		wantErr         bool
+		wantRetryable   bool
+		wantUnavailable bool
+	}{
+		{"ok", []int{200, 200}, 2, false, false, false},
+		{"retried rate limit", []int{200, 429, 200}, 3, false, false, false},
+		{"retries exhausted", []int{200, 500, 500, 500, 500}, 5, true, true, false},
+		{"blocked stops sending", []int{551}, 1, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, requests, closeStub := newFacebookStub(t, tt.responses)
+			defer closeStub()
+
+			p := New(f, log.NewNopLogger(), Options{Rate: 1000}).(*service)
+			p.dispatcher.backoff = 0
+
+			err := p.ProcessMessages("1", []string{"first.", "second."}, Update)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessMessages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(*requests) != tt.wantCalls {
+				t.Errorf("ProcessMessages() calls = %d, want %d", len(*requests), tt.wantCalls)
+			}
+			if IsRetryable(err) != tt.wantRetryable || IsRecipientUnavailable(err) != tt.wantUnavailable {
+				t.Errorf("ProcessMessages() unexpected error class %v", err)
+			}
+			for _, r := range *requests {
+				if r.Recipient.ID != "1" || r.Tag != "NON_PROMOTIONAL_SUBSCRIPTION" || r.MessagingType != "MESSAGE_TAG" {
+					t.Errorf("unexpected request %+v", r)
+				}
+			}
+		})
+	}
+}
+
+func TestFacebook_Send(t *testing.T) {
+	f, requests, bodies, closeStub := newFacebookRawStub(t, nil)
+	defer closeStub()
+
+	p := New(f, log.NewNopLogger(), Options{Rate: 1000})
+	err := p.Send("1", []Message{
+		{Text: "Read?", QuickReplies: []QuickReply{{Title: "Yes", Payload: "YES"}}},
+		{Card: &Card{Title: "J 3,16", Subtitle: "Tak bowiem Bóg umiłował świat", Buttons: []Button{
+			{Title: "Next", Payload: "NEXT"},
+			{Title: "Web", URL: "https://example.com"},
+		}}},
+		{Card: &Card{Title: "Ps 23,1", URL: "https://example.com/ps23"}},
+	}, Reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []m.Response{
+		{
+			MessagingType:    "RESPONSE",
+			NotificationType: "REGULAR",
+			Recipient:        m.User{ID: "1"},
+			Message: m.Message{
+				Text:         "Read?",
+				QuickReplies: []m.QuickReply{{ContentType: "text", Title: "Yes", Payload: "YES"}},
+			},
+		},
+		{
+			MessagingType:    "RESPONSE",
+			NotificationType: "REGULAR",
+			Recipient:        m.User{ID: "1"},
+			Message: m.Message{
+				Attachment: &m.Attachment{
+					Type: "template",
+					Payload: m.Payload{
+						TemplateType: "generic",
+						Elements: []m.Element{{
+							Title:    "J 3,16",
+							Subtitle: "Tak bowiem Bóg umiłował świat",
+							Buttons: []m.Button{
+								{Type: "postback", Title: "Next", Payload: "NEXT"},
+								{Type: "web_url", Title: "Web", URL: "https://example.com"},
+							},
+						}},
+					},
+				},
+			},
+		},
+		{
+			MessagingType:    "RESPONSE",
+			NotificationType: "REGULAR",
+			Recipient:        m.User{ID: "1"},
+			Message: m.Message{
+				Attachment: &m.Attachment{
+					Type: "template",
+					Payload: m.Payload{
+						TemplateType: "generic",
+						Elements: []m.Element{{
+							Title:         "Ps 23,1",
+							DefaultAction: &m.DefaultAction{Type: "web_url", URL: "https://example.com/ps23"},
+						}},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(*requests, want) {
+		t.Errorf("Send() requests = %+v, want %+v", *requests, want)
+	}
+
+	// Send API rejects empty default action, decoding above can't tell
+	// it apart from missing one.
+	if strings.Contains((*bodies)[1], "default_action") {
+		t.Errorf("card without URL sent with default action: %s", (*bodies)[1])
+	}
+	if !strings.Contains((*bodies)[2], `"default_action":{"type":"web_url","url":"https://example.com/ps23"}`) {
+		t.Errorf("card with URL sent without default action: %s", (*bodies)[2])
+	}
+}
+
+func Test_service_SendSplitsLongText(t *testing.T) {
+	f, requests, closeStub := newFacebookStub(t, nil)
+	defer closeStub()
+
+	long := strings.Repeat("a", facebookTextLength) + ". " + strings.Repeat("b", 10) + "."
+	p := New(f, log.NewNopLogger(), Options{Rate: 1000})
+	err := p.Send("1", []Message{{Text: long, QuickReplies: []QuickReply{{Title: "Read", Payload: "READ"}}}}, Update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("Send() calls = %d, want 2", len(*requests))
+	}
+	first, last := (*requests)[0].Message, (*requests)[1].Message
+	if first.Text+last.Text != long || first.QuickReplies != nil {
+		t.Errorf("unexpected first part %+v", first)
+	}
+	if want := []m.QuickReply{{ContentType: "text", Title: "Read", Payload: "READ"}}; !reflect.DeepEqual(last.QuickReplies, want) {
+		t.Errorf("last part quick replies = %+v, want %+v", last.QuickReplies, want)
+	}
+}
+
+func TestFacebook_Profile(t *testing.T) {
+	f, _, closeStub := newFacebookStub(t, nil)
+	defer closeStub()
+
+	tests := []struct {
+		id   string
+		want *Profile
+	}{
+		{"1", &Profile{Name: "Jan Kowalski", FirstName: "Jan", LastName: "Kowalski", Timezone: 2}},
+		{"2", &Profile{Name: "Ravi Kumar", FirstName: "Ravi", LastName: "Kumar", Timezone: 5.5}},
+	}
+	for _, tt := range tests {
+		got, err := f.Profile(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Profile(%s) = %+v, want %+v", tt.id, got, tt.want)
+		}
+	}
+}
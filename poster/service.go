@@ -1,37 +1,53 @@
 package poster
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"regexp"
 	"time"
 
 	"github.com/go-kit/kit/log"
-
-	m "github.com/jozuenoon/biblia2y/models"
 )
 
 type Service interface {
-	ProcessMessages(senderID string, messages []string, tag string, messagingType string, notificationType string) error
+	// ProcessMessages sends text messages, long texts are split
+	// to fit transport limits.
+	ProcessMessages(recipient string, messages []string, kind Kind) error
+	// Send sends rich messages, long texts are split like in ProcessMessages.
+	Send(recipient string, messages []Message, kind Kind) error
+	// Profile returns user details if transport supports it.
+	Profile(recipient string) (*Profile, error)
+	// Stats returns dispatcher queue metrics.
+	Stats() Stats
+	// Close stops sending, pending messages fail with retryable error.
+	Close()
 }
 
-func New(PageAccessToken, FaceBookAPI string, logger log.Logger) Service {
-	return &service{
-		logger:          logger,
-		PageAccessToken: PageAccessToken,
-		FaceBookAPI:     FaceBookAPI,
+const (
+	// Number of attempts of single message when error is retryable.
+	maxAttempts = 4
+	// Backoff before second attempt, doubled with every next one.
+	retryBackoff = time.Second
+	// Timeout of single API call.
+	requestTimeout = 30 * time.Second
+)
+
+func New(transport Transport, logger log.Logger, opts Options) Service {
+	p := &service{
+		logger:    logger,
+		transport: transport,
 	}
+	p.dispatcher = newDispatcher(opts, logger, p.post)
+	return p
 }
 
 type service struct {
-	PageAccessToken string
-	FaceBookAPI     string
-	logger          log.Logger
+	transport  Transport
+	logger     log.Logger
+	dispatcher *dispatcher
 }
 
+var sentenceEnd = regexp.MustCompile("([.])")
+
 // MessageSplitter will divide long messages into smaller pieces,
 // facebook API will accept only 2000 characters at once.
 func messageSplitter(input string, msgLen int, r *regexp.Regexp) []string {
@@ -50,55 +66,79 @@ func messageSplitter(input string, msgLen int, r *regexp.Regexp) []string {
 	return append([]string{input[:msgLen+idx[0]+1]}, messageSplitter(input[msgLen+idx[0]+1:], msgLen, r)...)
 }
 
-func (p *service) ProcessMessages(senderID string, messages []string, tag string, messagingType string, notificationType string) error {
-	client := &http.Client{}
-	responses := make([]m.Response, 0, len(messages))
-
-	r := regexp.MustCompile("([.])")
+// ProcessMessages sends messages in order through dispatcher, retryable
+// failures are retried with backoff. Returned error is Errors with
+// SendError for every message which couldn't be delivered.
+func (p *service) ProcessMessages(recipient string, messages []string, kind Kind) error {
+	msgs := make([]Message, 0, len(messages))
 
 	for _, verses := range messages {
-		for _, msg := range messageSplitter(verses, 1000, r) {
-			response := m.Response{
-				Recipient: m.User{
-					ID: senderID,
-				},
-				Message: m.Message{
-					Text: msg,
-				},
-				Tag:           tag,
-				MessagingType: messagingType,
-			}
-			responses = append(responses, response)
+		for _, msg := range p.split(verses) {
+			msgs = append(msgs, Message{Text: msg})
 		}
 	}
 
-	for _, response := range responses {
-		body := new(bytes.Buffer)
-		json.NewEncoder(body).Encode(&response)
+	return p.dispatcher.dispatch(recipient, msgs, kind)
+}
+
+// Send sends rich messages in order, texts which don't fit transport
+// limit are split and their quick replies go with the last part.
+func (p *service) Send(recipient string, messages []Message, kind Kind) error {
+	msgs := make([]Message, 0, len(messages))
 
-		url := fmt.Sprintf(p.FaceBookAPI, p.PageAccessToken)
-		req, err := http.NewRequest("POST", url, body)
-		if err != nil {
+	for _, msg := range messages {
+		if msg.Card != nil {
+			msgs = append(msgs, msg)
 			continue
 		}
-		req.Header.Add("Content-Type", "application/json")
-
-		resp, err := client.Do(req)
-		if err != nil {
-			p.logger.Log("msg", "post error to FB API", "err", err)
+		parts := p.split(msg.Text)
+		if len(parts) <= 1 {
+			msgs = append(msgs, msg)
 			continue
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode != 200 {
-			respInfo, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				p.logger.Log("msg", "post error to FB API", "err", err)
-				continue
+		for i, part := range parts {
+			m := Message{Text: part}
+			if i == len(parts)-1 {
+				m.QuickReplies = msg.QuickReplies
 			}
-			p.logger.Log("msg", "post returned", "err", string(respInfo))
-
+			msgs = append(msgs, m)
 		}
-		time.Sleep(2 * time.Second)
 	}
-	return nil
+
+	return p.dispatcher.dispatch(recipient, msgs, kind)
+}
+
+// split divides text into parts sent with single call each, so retry
+// of failed part doesn't repeat parts already delivered.
+func (p *service) split(text string) []string {
+	if s, ok := p.transport.(TextSplitter); ok {
+		return s.SplitText(text)
+	}
+	return messageSplitter(text, p.transport.MaxTextLength(), sentenceEnd)
+}
+
+func (p *service) Profile(recipient string) (*Profile, error) {
+	if pf, ok := p.transport.(ProfileFetcher); ok {
+		return pf.Profile(recipient)
+	}
+	return nil, fmt.Errorf("transport doesn't support user profiles")
+}
+
+func (p *service) Stats() Stats {
+	return p.dispatcher.stats()
+}
+
+func (p *service) Close() {
+	p.dispatcher.close()
+}
+
+func (p *service) post(recipient string, msg *Message, kind Kind) *SendError {
+	switch {
+	case msg.Card != nil:
+		return p.transport.SendCard(recipient, msg.Card, kind)
+	case len(msg.QuickReplies) > 0:
+		return p.transport.SendQuickReplies(recipient, msg.Text, msg.QuickReplies, kind)
+	default:
+		return p.transport.SendText(recipient, msg.Text, kind)
+	}
 }
@@ -0,0 +1,246 @@
+package poster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Telegram accepts up to 4096 characters (UTF-16 code units) after
+	// entities parsing, escaping adds characters so texts are split
+	// by SplitText and MaxTextLength is only a hint.
+	telegramTextLength = 3500
+	telegramTextLimit  = 4096
+
+	TelegramAPI = "https://api.telegram.org"
+)
+
+// Characters which have to be escaped in MarkdownV2 text.
+const telegramMarkdownSpecial = "_*[]()~`>#+-=|{}.!\\"
+
+// Telegram sends messages with Telegram Bot API.
+type Telegram struct {
+	// Bot API base URL, e.g. https://api.telegram.org.
+	API    string
+	Token  string
+	client *http.Client
+}
+
+var (
+	_ Transport    = (*Telegram)(nil)
+	_ TextSplitter = (*Telegram)(nil)
+)
+
+func NewTelegram(token, api string) *Telegram {
+	if api == "" {
+		api = TelegramAPI
+	}
+	return &Telegram{
+		API:    strings.TrimRight(api, "/"),
+		Token:  token,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+type telegramSendMessage struct {
+	ChatID              string                `json:"chat_id"`
+	Text                string                `json:"text"`
+	ParseMode           string                `json:"parse_mode,omitempty"`
+	DisableNotification bool                  `json:"disable_notification,omitempty"`
+	ReplyMarkup         *telegramInlineMarkup `json:"reply_markup,omitempty"`
+}
+
+type telegramInlineMarkup struct {
+	InlineKeyboard [][]telegramInlineButton `json:"inline_keyboard"`
+}
+
+type telegramInlineButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (t *Telegram) MaxTextLength() int {
+	return telegramTextLength
+}
+
+func (t *Telegram) SendText(recipient, text string, kind Kind) *SendError {
+	return t.SendQuickReplies(recipient, text, nil, kind)
+}
+
+// SplitText splits text into parts which fit Telegram limit once escaped.
+func (t *Telegram) SplitText(text string) []string {
+	return splitTelegramText(text, telegramTextLimit)
+}
+
+// SendQuickReplies sends text with quick replies as inline keyboard. Text
+// is expected to be split with SplitText, longer one is rejected by API.
+func (t *Telegram) SendQuickReplies(recipient, text string, replies []QuickReply, kind Kind) *SendError {
+	msg := t.message(recipient, escapeTelegramMarkdown(text), kind)
+	if len(replies) > 0 {
+		var row []telegramInlineButton
+		for _, r := range replies {
+			row = append(row, telegramInlineButton{Text: r.Title, CallbackData: r.Payload})
+		}
+		msg.ReplyMarkup = &telegramInlineMarkup{InlineKeyboard: [][]telegramInlineButton{row}}
+	}
+	return t.post("sendMessage", msg)
+}
+
+// SendCard sends card as text message with inline keyboard,
+// one button per row.
+func (t *Telegram) SendCard(recipient string, card *Card, kind Kind) *SendError {
+	lines := []string{"*" + escapeTelegramMarkdown(strings.Replace(card.Title, "*", "", -1)) + "*"}
+	if card.Subtitle != "" {
+		lines = append(lines, escapeTelegramMarkdown(card.Subtitle))
+	}
+	if card.URL != "" {
+		lines = append(lines, escapeTelegramMarkdown(card.URL))
+	}
+	msg := t.message(recipient, strings.Join(lines, "\n"), kind)
+	if len(card.Buttons) > 0 {
+		markup := &telegramInlineMarkup{}
+		for _, b := range card.Buttons {
+			button := telegramInlineButton{Text: b.Title}
+			if b.URL != "" {
+				button.URL = b.URL
+			} else {
+				button.CallbackData = b.Payload
+			}
+			markup.InlineKeyboard = append(markup.InlineKeyboard, []telegramInlineButton{button})
+		}
+		msg.ReplyMarkup = markup
+	}
+	return t.post("sendMessage", msg)
+}
+
+func (t *Telegram) message(recipient, text string, kind Kind) *telegramSendMessage {
+	return &telegramSendMessage{
+		ChatID:    recipient,
+		Text:      text,
+		ParseMode: "MarkdownV2",
+		// Scheduled updates shouldn't wake anybody up.
+		DisableNotification: kind == Update,
+	}
+}
+
+// escapeTelegramMarkdown escapes MarkdownV2 special characters. Texts use
+// *bold* markup (same as Messenger), so asterisks are kept when they pair up.
+func escapeTelegramMarkdown(text string) string {
+	keepBold := strings.Count(text, "*")%2 == 0
+	var b strings.Builder
+	for _, r := range text {
+		if r == '*' && keepBold {
+			b.WriteRune(r)
+			continue
+		}
+		if strings.ContainsRune(telegramMarkdownSpecial, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// splitTelegramText splits text into chunks of at most limit UTF-16 code
+// units once escaped, preferably at new line or space. Asterisks count
+// as escaped, bold markup may not pair up in chunk.
+func splitTelegramText(text string, limit int) []string {
+	var chunks []string
+	runes := []rune(text)
+	for len(runes) > 0 {
+		var size, end, cut int
+		for end < len(runes) {
+			n := 1
+			if runes[end] >= 0x10000 {
+				n = 2
+			}
+			if strings.ContainsRune(telegramMarkdownSpecial, runes[end]) {
+				n++
+			}
+			if size+n > limit {
+				break
+			}
+			size += n
+			end++
+		}
+		if end == len(runes) {
+			chunks = append(chunks, string(runes))
+			break
+		}
+		for i := end - 1; i > 0; i-- {
+			if runes[i] == '\n' || runes[i] == ' ' {
+				cut = i + 1
+				break
+			}
+		}
+		if cut == 0 {
+			cut = end
+		}
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return chunks
+}
+
+func (t *Telegram) post(method string, msg interface{}) *SendError {
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(msg); err != nil {
+		return &SendError{Err: err, Class: Permanent}
+	}
+
+	url := fmt.Sprintf("%s/bot%s/%s", t.API, t.Token, method)
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return &SendError{Err: err, Class: Permanent}
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return newTransportError(err)
+	}
+	defer resp.Body.Close()
+
+	respInfo, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return newTransportError(err)
+	}
+	if resp.StatusCode != 200 {
+		return newTelegramError(resp.StatusCode, respInfo)
+	}
+	return nil
+}
+
+// newTelegramError parses Bot API error response.
+func newTelegramError(statusCode int, body []byte) *SendError {
+	e := &SendError{StatusCode: statusCode}
+	var resp telegramResponse
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Description != "" {
+		e.Message = resp.Description
+		e.Code = resp.ErrorCode
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+
+	switch {
+	// Bot was blocked by the user, user is deactivated or kicked from chat.
+	case statusCode == 403:
+		e.Class = RecipientUnavailable
+	case statusCode == 400 && strings.Contains(strings.ToLower(e.Message), "chat not found"):
+		e.Class = RecipientUnavailable
+	default:
+		e.Class = classifyStatus(statusCode)
+	}
+	return e
+}
@@ -0,0 +1,156 @@
+package poster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestTelegram_Send(t *testing.T) {
+	var requests []telegramSendMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botsecret/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var msg telegramSendMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, msg)
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer srv.Close()
+
+	tr := NewTelegram("secret", srv.URL)
+	tr.client = srv.Client()
+
+	if err := tr.SendText("42", "hello", Update); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SendQuickReplies("42", "read?", []QuickReply{{Title: "Yes", Payload: "YES"}}, Reply); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.SendCard("42", &Card{Title: "J 3,16", Subtitle: "verse", Buttons: []Button{{Title: "Next", Payload: "NEXT"}, {Title: "Web", URL: "https://example.com"}}}, Reply); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []telegramSendMessage{
+		{ChatID: "42", Text: "hello", ParseMode: "MarkdownV2", DisableNotification: true},
+		{ChatID: "42", Text: "read?", ParseMode: "MarkdownV2", ReplyMarkup: &telegramInlineMarkup{
+			InlineKeyboard: [][]telegramInlineButton{{{Text: "Yes", CallbackData: "YES"}}},
+		}},
+		{ChatID: "42", Text: "*J 3,16*\nverse", ParseMode: "MarkdownV2", ReplyMarkup: &telegramInlineMarkup{
+			InlineKeyboard: [][]telegramInlineButton{
+				{{Text: "Next", CallbackData: "NEXT"}},
+				{{Text: "Web", URL: "https://example.com"}},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %+v, want %+v", requests, want)
+	}
+}
+
+func Test_service_SendTelegramRetriesFailedPart(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg telegramSendMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		texts = append(texts, msg.Text)
+		// Second part fails once.
+		if len(texts) == 2 {
+			w.WriteHeader(502)
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer srv.Close()
+
+	tr := NewTelegram("secret", srv.URL)
+	tr.client = srv.Client()
+	p := New(tr, log.NewNopLogger(), Options{Rate: 1000}).(*service)
+	p.dispatcher.backoff = 0
+
+	first, second := strings.Repeat("a", telegramTextLimit-1)+" ", "b"
+	if err := p.Send("42", []Message{{Text: first + second}}, Update); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{first, second, second}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("sent %d messages, want only failed part repeated", len(texts))
+	}
+}
+
+func Test_newTelegramError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantClass int
+	}{
+		{"blocked", 403, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`, RecipientUnavailable},
+		{"chat not found", 400, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`, RecipientUnavailable},
+		{"too many requests", 429, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 5","parameters":{"retry_after":5}}`, Retryable},
+		{"bad markup", 400, `{"ok":false,"error_code":400,"description":"Bad Request: can't parse entities"}`, Permanent},
+		{"gateway", 502, `Bad Gateway`, Retryable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTelegramError(tt.status, []byte(tt.body)); got.Class != tt.wantClass {
+				t.Errorf("newTelegramError() = %+v, want class %d", got, tt.wantClass)
+			}
+		})
+	}
+}
+
+func Test_escapeTelegramMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "Na początku było Słowo", "Na początku było Słowo"},
+		{"punctuation", "J 3,16. (Bóg) - tak!", "J 3,16\\. \\(Bóg\\) \\- tak\\!"},
+		{"bold kept", "*Help:*\n- *start*", "*Help:*\n\\- *start*"},
+		{"unpaired asterisk", "2*3", "2\\*3"},
+		{"backslash", `a\b`, `a\\b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeTelegramMarkdown(tt.text); got != tt.want {
+				t.Errorf("escapeTelegramMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitTelegramText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"fits", "ala ma kota", 11, []string{"ala ma kota"}},
+		{"at space", "ala ma kota", 8, []string{"ala ma ", "kota"}},
+		{"at new line", "ala\nma kota", 6, []string{"ala\n", "ma ", "kota"}},
+		{"no space", "abcdef", 4, []string{"abcd", "ef"}},
+		{"multibyte runes", "łódź", 2, []string{"łó", "dź"}},
+		{"surrogate pairs", "😀😀😀", 4, []string{"😀😀", "😀"}},
+		{"escaped characters", "a.b.c", 4, []string{"a.b", ".c"}},
+		{"asterisks", "*ab*", 4, []string{"*ab", "*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitTelegramText(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitTelegramText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package poster
+
+// Kind of outgoing message, transports map it to
+// channel specific delivery options.
+type Kind int
+
+const (
+	// Reply to message received from user.
+	Reply Kind = iota
+	// Scheduled update sent without user interaction.
+	Update
+)
+
+// Transport delivers messages over single channel (Messenger, Telegram...).
+// Every method sends exactly one message and returns *SendError on failure.
+type Transport interface {
+	SendText(recipient, text string, kind Kind) *SendError
+	SendCard(recipient string, card *Card, kind Kind) *SendError
+	SendQuickReplies(recipient, text string, replies []QuickReply, kind Kind) *SendError
+	// MaxTextLength returns maximum length of text in bytes accepted at once.
+	MaxTextLength() int
+}
+
+// TextSplitter is implemented by transports which can't tell whether
+// text fits by its length in bytes, e.g. because it's escaped before
+// sending. Every returned part has to fit single message.
+type TextSplitter interface {
+	SplitText(text string) []string
+}
+
+// ProfileFetcher is implemented by transports which can look up user details.
+type ProfileFetcher interface {
+	Profile(recipient string) (*Profile, error)
+}
+
+// Message is channel agnostic message, exactly one of Text,
+// Card is expected. QuickReplies are attached to Text.
+type Message struct {
+	Text         string
+	QuickReplies []QuickReply
+	Card         *Card
+}
+
+// Card is rich message with title, subtitle and buttons.
+type Card struct {
+	Title    string
+	Subtitle string
+	ImageURL string
+	URL      string
+	Buttons  []Button
+}
+
+// Button opens URL if set, otherwise sends Payload back.
+type Button struct {
+	Title   string
+	URL     string
+	Payload string
+}
+
+// QuickReply is button displayed under message which sends Payload back.
+type QuickReply struct {
+	Title   string
+	Payload string
+}
+
+// Profile holds user details reported by channel.
+type Profile struct {
+	Name      string
+	FirstName string
+	LastName  string
+	// Offset from UTC in hours, could be fractional, e.g. 5.5.
+	Timezone float64
+}
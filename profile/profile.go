@@ -0,0 +1,150 @@
+// Package profile configures page settings with Messenger Profile API:
+// Get Started button, greeting and persistent menu.
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	m "github.com/jozuenoon/biblia2y/models"
+)
+
+const (
+	// Messenger Profile API URL with %s placeholder for access token.
+	GraphAPI = "https://graph.facebook.com/v2.6/me/messenger_profile?access_token=%s"
+
+	requestTimeout = 30 * time.Second
+)
+
+// Profile fields managed by Sync.
+const (
+	FieldGetStarted     = "get_started"
+	FieldGreeting       = "greeting"
+	FieldPersistentMenu = "persistent_menu"
+)
+
+// API reads and updates Messenger Profile of the page.
+type API interface {
+	// Get returns current values of fields.
+	Get(fields []string) (*m.MessengerProfile, error)
+	// Set updates fields which are set in profile, others are kept.
+	Set(profile *m.MessengerProfile) error
+}
+
+// Graph implements API with Graph API calls.
+type Graph struct {
+	PageAccessToken string
+	// Messenger Profile API URL with %s placeholder for access token.
+	ProfileAPI string
+	client     *http.Client
+}
+
+var _ API = (*Graph)(nil)
+
+func NewGraph(pageAccessToken, profileAPI string) *Graph {
+	if profileAPI == "" {
+		profileAPI = GraphAPI
+	}
+	return &Graph{
+		PageAccessToken: pageAccessToken,
+		ProfileAPI:      profileAPI,
+		client:          &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (g *Graph) Get(fields []string) (*m.MessengerProfile, error) {
+	url := fmt.Sprintf(g.ProfileAPI, g.PageAccessToken) + "&fields=" + strings.Join(fields, ",")
+	body, err := g.do("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp m.MessengerProfileResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return &m.MessengerProfile{}, nil
+	}
+	return &resp.Data[0], nil
+}
+
+func (g *Graph) Set(profile *m.MessengerProfile) error {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	_, err = g.do("POST", fmt.Sprintf(g.ProfileAPI, g.PageAccessToken), data)
+	return err
+}
+
+func (g *Graph) do(method, url string, data []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		var e m.ErrorResponse
+		if err := json.Unmarshal(body, &e); err == nil && e.Error.Message != "" {
+			return nil, fmt.Errorf("messenger profile %s failed with status %d: %s (code %d, fbtrace_id %s)",
+				method, resp.StatusCode, e.Error.Message, e.Error.Code, e.Error.FBTraceID)
+		}
+		return nil, fmt.Errorf("messenger profile %s failed with status %d: %s", method, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// Sync updates fields of current profile which differ from want, fields
+// not set in want are left untouched. Returns names of updated fields.
+func Sync(api API, want *m.MessengerProfile, logger log.Logger) ([]string, error) {
+	current, err := api.Get([]string{FieldGetStarted, FieldGreeting, FieldPersistentMenu})
+	if err != nil {
+		return nil, err
+	}
+
+	update := &m.MessengerProfile{}
+	var changed []string
+	if want.GetStarted != nil && !reflect.DeepEqual(want.GetStarted, current.GetStarted) {
+		update.GetStarted = want.GetStarted
+		changed = append(changed, FieldGetStarted)
+	}
+	if want.Greeting != nil && !reflect.DeepEqual(want.Greeting, current.Greeting) {
+		update.Greeting = want.Greeting
+		changed = append(changed, FieldGreeting)
+	}
+	if want.PersistentMenu != nil && !reflect.DeepEqual(want.PersistentMenu, current.PersistentMenu) {
+		// Persistent menu can't be set without Get Started button.
+		if update.GetStarted == nil && current.GetStarted == nil && want.GetStarted == nil {
+			return nil, fmt.Errorf("persistent menu requires get started button")
+		}
+		update.PersistentMenu = want.PersistentMenu
+		changed = append(changed, FieldPersistentMenu)
+	}
+
+	if len(changed) == 0 {
+		logger.Log("msg", "messenger profile is up to date")
+		return nil, nil
+	}
+	if err := api.Set(update); err != nil {
+		return nil, err
+	}
+	logger.Log("msg", "messenger profile updated", "fields", strings.Join(changed, ","))
+	return changed, nil
+}
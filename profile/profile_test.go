@@ -0,0 +1,150 @@
+package profile
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	m "github.com/jozuenoon/biblia2y/models"
+)
+
+// newGraphStub returns client of local Messenger Profile API stand-in
+// which keeps profile in memory and records update requests.
+func newGraphStub(t *testing.T, current *m.MessengerProfile) (*Graph, *[]map[string]json.RawMessage, func()) {
+	var posts []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != "token" {
+			t.Errorf("missing access token: %s", r.URL)
+		}
+		switch r.Method {
+		case "GET":
+			if got := r.URL.Query().Get("fields"); got != "get_started,greeting,persistent_menu" {
+				t.Errorf("unexpected fields %q", got)
+			}
+			json.NewEncoder(w).Encode(m.MessengerProfileResponse{Data: []m.MessengerProfile{*current}})
+		case "POST":
+			var fields map[string]json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+				t.Error(err)
+			}
+			posts = append(posts, fields)
+			var update m.MessengerProfile
+			b, _ := json.Marshal(fields)
+			json.Unmarshal(b, &update)
+			if update.GetStarted != nil {
+				current.GetStarted = update.GetStarted
+			}
+			if update.Greeting != nil {
+				current.Greeting = update.Greeting
+			}
+			if update.PersistentMenu != nil {
+				current.PersistentMenu = update.PersistentMenu
+			}
+			w.Write([]byte(`{"result":"success"}`))
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+
+	g := NewGraph("token", srv.URL+"/me/messenger_profile?access_token=%s")
+	g.client = srv.Client()
+	return g, &posts, srv.Close
+}
+
+func testProfile() *m.MessengerProfile {
+	return &m.MessengerProfile{
+		GetStarted: &m.GetStarted{Payload: "GET_STARTED"},
+		Greeting:   []m.Greeting{{Locale: "default", Text: "Hello"}},
+		PersistentMenu: []m.PersistentMenu{{
+			Locale: "default",
+			CallToActions: []m.MenuItem{
+				{Type: "postback", Title: "Info", Payload: "INFO"},
+				{Type: "nested", Title: "More", CallToActions: []m.MenuItem{
+					{Type: "postback", Title: "Stop", Payload: "STOP"},
+				}},
+			},
+		}},
+	}
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     *m.MessengerProfile
+		wantChanged []string
+	}{
+		{"empty profile", &m.MessengerProfile{}, []string{FieldGetStarted, FieldGreeting, FieldPersistentMenu}},
+		{"up to date", testProfile(), nil},
+		{"greeting changed", func() *m.MessengerProfile {
+			p := testProfile()
+			p.Greeting[0].Text = "Old greeting"
+			return p
+		}(), []string{FieldGreeting}},
+		{"menu changed", func() *m.MessengerProfile {
+			p := testProfile()
+			p.PersistentMenu[0].CallToActions = p.PersistentMenu[0].CallToActions[:1]
+			return p
+		}(), []string{FieldPersistentMenu}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, posts, closeStub := newGraphStub(t, tt.current)
+			defer closeStub()
+
+			changed, err := Sync(g, testProfile(), log.NewNopLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(changed, tt.wantChanged) {
+				t.Errorf("Sync() = %v, want %v", changed, tt.wantChanged)
+			}
+			// Only changed fields are sent.
+			if len(tt.wantChanged) == 0 && len(*posts) != 0 || len(tt.wantChanged) > 0 && len((*posts)[0]) != len(tt.wantChanged) {
+				t.Errorf("unexpected updates %v", *posts)
+			}
+			if !reflect.DeepEqual(tt.current, testProfile()) {
+				t.Errorf("profile after Sync() = %+v", tt.current)
+			}
+
+			// Second run is a no-op.
+			changed, err = Sync(g, testProfile(), log.NewNopLogger())
+			if err != nil || changed != nil {
+				t.Errorf("second Sync() = %v, %v", changed, err)
+			}
+		})
+	}
+}
+
+func TestSync_menuWithoutGetStarted(t *testing.T) {
+	g, posts, closeStub := newGraphStub(t, &m.MessengerProfile{})
+	defer closeStub()
+
+	want := testProfile()
+	want.GetStarted = nil
+	if _, err := Sync(g, want, log.NewNopLogger()); err == nil {
+		t.Error("Sync() expected error")
+	}
+	if len(*posts) != 0 {
+		t.Errorf("unexpected updates %v", *posts)
+	}
+}
+
+func TestGraph_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error":{"message":"(#100) Invalid keys \"foo\"","type":"OAuthException","code":100,"fbtrace_id":"x"}}`))
+	}))
+	defer srv.Close()
+
+	g := NewGraph("token", srv.URL+"?access_token=%s")
+	g.client = srv.Client()
+	if _, err := g.Get([]string{"foo"}); err == nil {
+		t.Error("Get() expected error")
+	}
+	if err := g.Set(testProfile()); err == nil {
+		t.Error("Set() expected error")
+	}
+}